@@ -0,0 +1,627 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/utils"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goDecl describes an exported package level declaration of the generated bindings for one arch
+type goDecl struct {
+	Kind string //func, var, const, alias, type, struct or interface
+
+	//func: the signature without parameter names, var and const: the type,
+	//alias: the aliased type, type: the underlying type
+	Type  string
+	Value string //const
+
+	Size    int64 //struct
+	Align   int64
+	Fields  []goField         //struct: the exported fields, all the slots of vtables
+	Vtbl    bool              //struct: the vtable of a com interface, IFooVtbl
+	Methods map[string]string //interface: its methods, other types: the method set of their pointer
+}
+
+type goField struct {
+	Name   string
+	Type   string
+	Offset int64
+}
+
+// goApiChange is a difference between the declarations of two generated outputs
+type goApiChange struct {
+	Breaking bool     `json:"breaking"`
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Message  string   `json:"message"`
+	Arches   []string `json:"arches"`
+}
+
+// runApiCheck compares the go api of two generated outputs, it exits with status 1
+// when a change breaks code using the old bindings
+func runApiCheck(args []string) {
+	fs := flag.NewFlagSet("apicheck", flag.ExitOnError)
+	archList := fs.String("arch", "", "comma separated architectures to compare: X64, X86, Arm64,\n"+
+		"by default those the build constraints of the outputs select, X64 without any")
+	module := fs.String("module", "", "import path the outputs were generated with, required with -split")
+	jsonOutput := fs.Bool("json", false, "write the changes as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-win32api-gen apicheck [flags] old/win32 new/win32")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var arches []utils.Arch
+	var err error
+	if *archList == "" {
+		arches, err = outputArches(fs.Arg(0), fs.Arg(1))
+	} else {
+		arches, err = utils.ParseArches(*archList)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes, err := checkGoApi(fs.Arg(0), fs.Arg(1), *module, arches)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOutput {
+		err = writeApiCheckJson(os.Stdout, changes)
+	} else {
+		err = writeApiCheck(os.Stdout, changes, len(arches))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, it := range changes {
+		if it.Breaking {
+			os.Exit(1)
+		}
+	}
+}
+
+// checkGoApi type checks both outputs for each arch and compares their declarations,
+// changes found for several arches are reported once
+func checkGoApi(oldDir string, newDir string, module string, arches []utils.Arch) ([]goApiChange, error) {
+	diag.Reset()
+	var changes []goApiChange
+	index := make(map[string]int)
+	for _, arch := range arches {
+		//the standard packages are imported from source, for windows, without the
+		//experiment tags of the host arch
+		ctx := build.Default
+		ctx.GOOS, ctx.GOARCH = "windows", arch.GoArch
+		ctx.CgoEnabled = false
+		ctx.ToolTags = nil
+		fset := token.NewFileSet()
+		std := &stdImporter{
+			fset:  fset,
+			ctx:   &ctx,
+			sizes: types.SizesFor("gc", arch.GoArch),
+			pkgs:  make(map[string]*types.Package),
+		}
+
+		oldDecls, err := loadGoApi(fset, std, oldDir, module, arch)
+		if err != nil {
+			return nil, err
+		}
+		newDecls, err := loadGoApi(fset, std, newDir, module, arch)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range diffGoApi(oldDecls, newDecls) {
+			key := fmt.Sprint(it.Breaking, "\n", it.Kind, "\n", it.Name, "\n", it.Message)
+			if n, ok := index[key]; ok {
+				changes[n].Arches = append(changes[n].Arches, arch.Name)
+				continue
+			}
+			index[key] = len(changes)
+			it.Arches = []string{arch.Name}
+			changes = append(changes, it)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// outputArches returns the arches the build constraints of the go files under dirs select,
+// X64 when there are none, as in the output generated for a single arch
+func outputArches(dirs ...string) ([]utils.Arch, error) {
+	found := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(filePath string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || !strings.HasSuffix(filePath, ".go") {
+				return err
+			}
+			expr, err := readBuildConstraint(filePath)
+			if err != nil || expr == nil {
+				return err
+			}
+			for _, arch := range utils.Arches {
+				goArch := arch.GoArch
+				if expr.Eval(func(tag string) bool { return tag == goArch || tag == "windows" }) {
+					found[arch.Name] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var arches []utils.Arch
+	for _, arch := range utils.Arches {
+		if found[arch.Name] {
+			arches = append(arches, arch)
+		}
+	}
+	if len(arches) == 0 {
+		arches = utils.Arches[:1]
+	}
+	return arches, nil
+}
+
+// readBuildConstraint returns the //go:build constraint of a go file, nil if it has none
+func readBuildConstraint(filePath string) (constraint.Expr, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if constraint.IsGoBuild(line) {
+			return constraint.Parse(line)
+		}
+		if line != "" && !strings.HasPrefix(line, "//") {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// stdImporter type checks the standard packages from source for the build context of an arch,
+// without function bodies
+type stdImporter struct {
+	fset  *token.FileSet
+	ctx   *build.Context
+	sizes types.Sizes
+	pkgs  map[string]*types.Package
+}
+
+func (this *stdImporter) Import(importPath string) (*types.Package, error) {
+	return this.ImportFrom(importPath, "", 0)
+}
+
+func (this *stdImporter) ImportFrom(importPath string, dir string, mode types.ImportMode) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := this.ctx.Import(importPath, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := this.pkgs[bp.ImportPath]; ok {
+		return pkg, nil
+	}
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(this.fset, filepath.Join(bp.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: this, Sizes: this.sizes, IgnoreFuncBodies: true}
+	pkg, err := conf.Check(bp.ImportPath, this.fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	this.pkgs[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// goApiImporter type checks the generated packages under dir,
+// the other packages are imported by std
+type goApiImporter struct {
+	fset   *token.FileSet
+	std    *stdImporter
+	ctx    build.Context
+	sizes  types.Sizes
+	dir    string
+	module string
+	pkgs   map[string]*types.Package
+}
+
+func (this *goApiImporter) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := this.pkgs[importPath]; ok {
+		return pkg, nil
+	}
+	if importPath != this.module && !strings.HasPrefix(importPath, this.module+"/") {
+		return this.std.Import(importPath)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, this.module), "/")
+	return this.check(importPath, filepath.Join(this.dir, filepath.FromSlash(rel)))
+}
+
+// check type checks the package in dir with the files of the arch
+func (this *goApiImporter) check(importPath string, dir string) (*types.Package, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := this.ctx.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		file, err := parser.ParseFile(this.fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	//type errors are reported and checking goes on, the declarations involved
+	//may differ only by their invalid types
+	conf := types.Config{Importer: this, Sizes: this.sizes, Error: func(err error) {
+		if e, ok := err.(types.Error); ok {
+			diag.Warn(diag.Location{File: e.Fset.Position(e.Pos).String()}, "%s", e.Msg)
+		}
+	}}
+	pkg, _ := conf.Check(importPath, this.fset, files, nil)
+	this.pkgs[importPath] = pkg
+	return pkg, nil
+}
+
+// loadGoApi type checks the packages generated in dir for arch and describes their declarations
+// by name, qualified by the directory of their package unless it is dir
+func loadGoApi(fset *token.FileSet, std *stdImporter, dir string, module string,
+	arch utils.Arch) (map[string]*goDecl, error) {
+
+	imp := &goApiImporter{
+		fset:   fset,
+		std:    std,
+		ctx:    *std.ctx,
+		sizes:  types.SizesFor("gc", arch.GoArch),
+		dir:    dir,
+		module: module,
+		pkgs:   make(map[string]*types.Package),
+	}
+	if module == "" {
+		imp.module = "win32"
+	}
+	pkgDirs := make(map[string]bool)
+	err := filepath.Walk(dir, func(filePath string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() && strings.HasSuffix(filePath, ".go") {
+			pkgDirs[filepath.Dir(filePath)] = true
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	decls := make(map[string]*goDecl)
+	for pkgDir := range pkgDirs {
+		rel, _ := filepath.Rel(dir, pkgDir)
+		rel = filepath.ToSlash(rel)
+		pkg, err := imp.Import(path.Join(imp.module, rel))
+		if err != nil {
+			return nil, err
+		}
+		prefix := ""
+		if rel != "." {
+			prefix = rel + "."
+		}
+		for _, name := range pkg.Scope().Names() {
+			if obj := pkg.Scope().Lookup(name); obj.Exported() {
+				decls[prefix+name] = describeGoDecl(obj, imp.sizes)
+			}
+		}
+	}
+	return decls, nil
+}
+
+// describeGoDecl describes the declaration of obj, types of other packages are
+// qualified by their import path
+func describeGoDecl(obj types.Object, sizes types.Sizes) *goDecl {
+	qualifier := func(pkg *types.Package) string {
+		if pkg == obj.Pkg() {
+			return ""
+		}
+		return pkg.Path()
+	}
+	typeString := func(t types.Type) string {
+		return types.TypeString(t, qualifier)
+	}
+
+	decl := &goDecl{}
+	switch obj := obj.(type) {
+	case *types.Func:
+		decl.Kind = "func"
+		decl.Type = goSignature(obj.Type().(*types.Signature), qualifier)
+	case *types.Var:
+		decl.Kind = "var"
+		decl.Type = typeString(obj.Type())
+	case *types.Const:
+		decl.Kind = "const"
+		decl.Type = typeString(obj.Type())
+		decl.Value = obj.Val().ExactString()
+	case *types.TypeName:
+		if obj.IsAlias() {
+			decl.Kind = "alias"
+			decl.Type = typeString(obj.Type())
+			break
+		}
+		decl.Methods = make(map[string]string)
+		switch t := obj.Type().Underlying().(type) {
+		case *types.Struct:
+			decl.Kind = "struct"
+			decl.Size, decl.Align = sizes.Sizeof(t), sizes.Alignof(t)
+			decl.Vtbl = strings.HasSuffix(obj.Name(), "Vtbl")
+			var fields []*types.Var
+			for n := 0; n < t.NumFields(); n++ {
+				fields = append(fields, t.Field(n))
+			}
+			offsets := sizes.Offsetsof(fields)
+			for n, it := range fields {
+				if decl.Vtbl {
+					//the offsets of vtables are slots, after those of the base interfaces
+					offsets[n] /= sizes.Sizeof(types.Typ[types.Uintptr])
+				}
+				if it.Exported() {
+					decl.Fields = append(decl.Fields, goField{it.Name(), typeString(it.Type()), offsets[n]})
+				}
+			}
+		case *types.Interface:
+			decl.Kind = "interface"
+			for n := 0; n < t.NumMethods(); n++ {
+				m := t.Method(n)
+				decl.Methods[m.Name()] = goSignature(m.Type().(*types.Signature), qualifier)
+			}
+			return decl
+		default:
+			decl.Kind = "type"
+			decl.Type = typeString(t)
+		}
+		methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+		for n := 0; n < methods.Len(); n++ {
+			if m := methods.At(n).Obj(); m.Exported() {
+				decl.Methods[m.Name()] = goSignature(m.Type().(*types.Signature), qualifier)
+			}
+		}
+	}
+	return decl
+}
+
+// goSignature formats a signature without its parameter names, they do not matter to callers
+func goSignature(sig *types.Signature, qualifier types.Qualifier) string {
+	tupleString := func(tuple *types.Tuple, variadic bool) string {
+		var s []string
+		for n := 0; n < tuple.Len(); n++ {
+			t := tuple.At(n).Type()
+			if variadic && n == tuple.Len()-1 {
+				s = append(s, "..."+types.TypeString(t.(*types.Slice).Elem(), qualifier))
+			} else {
+				s = append(s, types.TypeString(t, qualifier))
+			}
+		}
+		return strings.Join(s, ", ")
+	}
+	s := "func(" + tupleString(sig.Params(), sig.Variadic()) + ")"
+	switch sig.Results().Len() {
+	case 0:
+	case 1:
+		s += " " + tupleString(sig.Results(), false)
+	default:
+		s += " (" + tupleString(sig.Results(), false) + ")"
+	}
+	return s
+}
+
+// diffGoApi compares the declarations of two outputs. Removing or changing what callers use
+// is breaking, adding is compatible, except methods to the interfaces go values implement.
+func diffGoApi(oldDecls map[string]*goDecl, newDecls map[string]*goDecl) []goApiChange {
+	var changes []goApiChange
+	for name, from := range oldDecls {
+		if to, ok := newDecls[name]; ok {
+			for _, it := range diffGoDecl(from, to) {
+				it.Kind, it.Name = from.Kind, name
+				changes = append(changes, it)
+			}
+		} else {
+			changes = append(changes, goApiChange{Breaking: true, Kind: from.Kind, Name: name, Message: "removed"})
+		}
+	}
+	for name, to := range newDecls {
+		if _, ok := oldDecls[name]; !ok {
+			changes = append(changes, goApiChange{Kind: to.Kind, Name: name, Message: "added"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Message < changes[j].Message
+	})
+	return changes
+}
+
+// diffGoDecl compares two declarations of the same name, the changes are returned without
+// their kind and name
+func diffGoDecl(from *goDecl, to *goDecl) []goApiChange {
+	var changes []goApiChange
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, goApiChange{Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+	}
+	if from.Kind != to.Kind {
+		add(true, "kind: %s -> %s", from.Kind, to.Kind)
+		return changes
+	}
+	switch {
+	case from.Type != to.Type:
+		add(true, "type: %s -> %s", from.Type, to.Type)
+	case from.Value != to.Value:
+		add(true, "value: %s -> %s", from.Value, to.Value)
+	}
+	if from.Vtbl && to.Vtbl {
+		changes = append(changes, diffVtblSlots(from.Fields, to.Fields)...)
+	} else if from.Kind == "struct" {
+		if from.Size != to.Size {
+			add(true, "size: %d -> %d", from.Size, to.Size)
+		}
+		if from.Align != to.Align {
+			add(true, "alignment: %d -> %d", from.Align, to.Align)
+		}
+		changes = append(changes, diffGoFields(from.Fields, to.Fields)...)
+	}
+	changes = append(changes, diffGoMethods(from.Methods, to.Methods, from.Kind == "interface")...)
+	return changes
+}
+
+// diffGoFields compares the exported fields of two structs
+func diffGoFields(from []goField, to []goField) []goApiChange {
+	var changes []goApiChange
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, goApiChange{Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+	}
+	toFields := make(map[string]goField)
+	for _, it := range to {
+		toFields[it.Name] = it
+	}
+	fromFields := make(map[string]bool)
+	for _, f := range from {
+		fromFields[f.Name] = true
+		t, ok := toFields[f.Name]
+		switch {
+		case !ok:
+			add(true, "field removed: %s %s", f.Name, f.Type)
+		case f.Type != t.Type:
+			add(true, "field %s: type %s -> %s", f.Name, f.Type, t.Type)
+		case f.Offset != t.Offset:
+			add(true, "field %s: offset %d -> %d", f.Name, f.Offset, t.Offset)
+		}
+	}
+	for _, t := range to {
+		if !fromFields[t.Name] {
+			add(false, "field added: %s %s at %d", t.Name, t.Type, t.Offset)
+		}
+	}
+	return changes
+}
+
+// diffVtblSlots compares the method slots of two com vtables, methods appended to
+// the vtable are compatible, other changes call the wrong methods
+func diffVtblSlots(from []goField, to []goField) []goApiChange {
+	var changes []goApiChange
+	toSlots := make(map[string]int64)
+	for _, it := range to {
+		toSlots[it.Name] = it.Offset
+	}
+	fromSlots := make(map[string]bool)
+	lastSlot := int64(-1)
+	for _, it := range from {
+		fromSlots[it.Name] = true
+		if it.Offset > lastSlot {
+			lastSlot = it.Offset
+		}
+		if slot, ok := toSlots[it.Name]; !ok {
+			changes = append(changes, goApiChange{Breaking: true, Message: "method removed: " + it.Name})
+		} else if slot != it.Offset {
+			changes = append(changes, goApiChange{Breaking: true,
+				Message: fmt.Sprintf("method %s: slot %d -> %d", it.Name, it.Offset, slot)})
+		}
+	}
+	for _, it := range to {
+		if !fromSlots[it.Name] {
+			changes = append(changes, goApiChange{Breaking: it.Offset <= lastSlot,
+				Message: fmt.Sprintf("method added: %s at slot %d", it.Name, it.Offset)})
+		}
+	}
+	return changes
+}
+
+// diffGoMethods compares the methods of two types, adding a method to an interface breaks
+// the types implementing it
+func diffGoMethods(from map[string]string, to map[string]string, iface bool) []goApiChange {
+	var changes []goApiChange
+	var names []string
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, inFrom := from[name]
+		t, inTo := to[name]
+		switch {
+		case !inTo:
+			changes = append(changes, goApiChange{Breaking: true, Message: "method removed: " + name + f[len("func"):]})
+		case !inFrom:
+			changes = append(changes, goApiChange{Breaking: iface, Message: "method added: " + name + t[len("func"):]})
+		case f != t:
+			changes = append(changes, goApiChange{Breaking: true,
+				Message: fmt.Sprintf("method %s: %s -> %s", name, f, t)})
+		}
+	}
+	return changes
+}
+
+// writeApiCheck writes a line per change, marked ! when it is breaking and + when it is
+// compatible, followed by the arches it was found on unless it was found on all of them
+func writeApiCheck(w io.Writer, changes []goApiChange, archCount int) error {
+	breaking := 0
+	for _, it := range changes {
+		mark := "+"
+		if it.Breaking {
+			mark = "!"
+			breaking++
+		}
+		line := mark + " " + it.Kind + " " + it.Name + ": " + it.Message
+		if len(it.Arches) != archCount {
+			line += " (" + strings.Join(it.Arches, ", ") + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d breaking, %d compatible\n", breaking, len(changes)-breaking)
+	return err
+}
+
+func writeApiCheckJson(w io.Writer, changes []goApiChange) error {
+	if changes == nil {
+		changes = []goApiChange{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"go-win32api-gen/codegen"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/utils"
+	"reflect"
+	"strconv"
+)
+
+// declaration lists of gomodel.GoApi split per declaration,
+// aliases follow the declaration they refer to
+var archDeclFields = []string{
+	"TypeAliases", "Handles", "Consts", "VarConsts", "Enums",
+	"Structs", "FuncTypes", "Funcs", "Coms", "ComClasses",
+}
+
+type archDecl struct {
+	value reflect.Value
+	code  string
+}
+
+// newGoApiLike returns an empty go api with the file level settings of goApi
+func newGoApiLike(goApi *gomodel.GoApi) *gomodel.GoApi {
+	return &gomodel.GoApi{
+		Name:        goApi.Name,
+		Package:     goApi.Package,
+		ErrorType:   goApi.ErrorType,
+		ErrorValues: goApi.ErrorValues,
+		Wrappers:    goApi.Wrappers,
+		LeakCheck:   goApi.LeakCheck,
+
+		CoCreateInstance: goApi.CoCreateInstance,
+	}
+}
+
+// renderDecl generates the code of a single declaration for the current arch
+func renderDecl(goApi *gomodel.GoApi, field string, decl reflect.Value) string {
+	one := newGoApiLike(goApi)
+	list := reflect.MakeSlice(reflect.SliceOf(decl.Type().Elem()), 0, 1)
+	list = reflect.Append(list, decl.Elem())
+	reflect.ValueOf(one).Elem().FieldByName(field).Set(list)
+	w := bytes.NewBuffer(nil)
+	codegen.Gen(one, w)
+	return w.String()
+}
+
+// collectArchDecls returns the declarations of a list keyed by name,
+// names repeated in the list get an occurrence suffix
+func collectArchDecls(goApi *gomodel.GoApi, field string) ([]string, map[string]archDecl) {
+	var keys []string
+	decls := make(map[string]archDecl)
+	if goApi == nil {
+		return keys, decls
+	}
+	list := reflect.ValueOf(goApi).Elem().FieldByName(field)
+	for n := 0; n < list.Len(); n++ {
+		item := list.Index(n)
+		key := item.FieldByName("Name").String()
+		for m := 2; ; m++ {
+			if _, ok := decls[key]; !ok {
+				break
+			}
+			key = item.FieldByName("Name").String() + "#" + strconv.Itoa(m)
+		}
+		keys = append(keys, key)
+		ptr := reflect.New(item.Type())
+		ptr.Elem().Set(item)
+		decls[key] = archDecl{value: ptr, code: renderDecl(goApi, field, ptr)}
+	}
+	return keys, decls
+}
+
+// splitByArch splits the go apis generated for each arch into the declarations
+// identical on all arches and the arch specific ones. An arch api is nil if it is empty.
+func splitByArch(arches []utils.Arch, goApis []*gomodel.GoApi) (*gomodel.GoApi, []*gomodel.GoApi) {
+	var first *gomodel.GoApi
+	for _, goApi := range goApis {
+		if goApi != nil {
+			first = goApi
+			break
+		}
+	}
+	common := newGoApiLike(first)
+	archApis := make([]*gomodel.GoApi, len(arches))
+	for n := range arches {
+		archApis[n] = newGoApiLike(first)
+	}
+
+	for _, field := range archDeclFields {
+		keyLists := make([][]string, len(arches))
+		declMaps := make([]map[string]archDecl, len(arches))
+		for n, arch := range arches {
+			utils.SetArch(arch)
+			keyLists[n], declMaps[n] = collectArchDecls(goApis[n], field)
+		}
+		for n := range arches {
+			for _, key := range keyLists[n] {
+				decl := declMaps[n][key]
+				isCommon := true
+				for m := range arches {
+					other, ok := declMaps[m][key]
+					if !ok || other.code != decl.code {
+						isCommon = false
+						break
+					}
+				}
+				target := archApis[n]
+				if isCommon {
+					if n > 0 {
+						continue
+					}
+					target = common
+				}
+				list := reflect.ValueOf(target).Elem().FieldByName(field)
+				list.Set(reflect.Append(list, decl.value.Elem()))
+			}
+		}
+	}
+
+	splitAliases(common, archApis, goApis, arches)
+
+	for n, archApi := range archApis {
+		if isEmptyGoApi(archApi) {
+			archApis[n] = nil
+		}
+	}
+	return common, archApis
+}
+
+func splitAliases(common *gomodel.GoApi, archApis []*gomodel.GoApi,
+	goApis []*gomodel.GoApi, arches []utils.Arch) {
+
+	hasFunc := func(goApi *gomodel.GoApi, name string) bool {
+		for _, f := range goApi.Funcs {
+			if f.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	hasStruct := func(goApi *gomodel.GoApi, name string) bool {
+		for _, s := range goApi.Structs {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	for n := range arches {
+		if goApis[n] == nil {
+			continue
+		}
+		for _, a := range goApis[n].FuncAliases {
+			if hasFunc(archApis[n], a.RealName) {
+				archApis[n].FuncAliases = append(archApis[n].FuncAliases, a)
+			} else if n == 0 && hasFunc(common, a.RealName) {
+				common.FuncAliases = append(common.FuncAliases, a)
+			}
+		}
+		for _, a := range goApis[n].StructAliases {
+			if hasStruct(archApis[n], a.RealName) {
+				archApis[n].StructAliases = append(archApis[n].StructAliases, a)
+			} else if n == 0 && hasStruct(common, a.RealName) {
+				common.StructAliases = append(common.StructAliases, a)
+			}
+		}
+	}
+}
+
+func isEmptyGoApi(goApi *gomodel.GoApi) bool {
+	v := reflect.ValueOf(goApi).Elem()
+	for _, field := range archDeclFields {
+		if v.FieldByName(field).Len() > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"go-win32api-gen/diag"
+	"go-win32api-gen/jsonmodel"
+	"io/ioutil"
+	"strings"
+)
+
+// fq names of the types, functions and constants generated with -roots,
+// nil if all declarations are generated
+var gClosure map[string]bool
+
+// roots that matched a declaration for some arch
+var gMatchedRoots map[string]bool
+
+// parseRoots returns the root symbols of a -roots value, a comma separated list,
+// or @file naming a file that lists them separated by white space, with # comments
+func parseRoots(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "@") {
+		var roots []string
+		for _, it := range strings.Split(value, ",") {
+			if it = strings.TrimSpace(it); it != "" {
+				roots = append(roots, it)
+			}
+		}
+		return roots, nil
+	}
+	data, err := ioutil.ReadFile(value[1:])
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if n := strings.IndexByte(line, '#'); n >= 0 {
+			line = line[:n]
+		}
+		roots = append(roots, strings.Fields(line)...)
+	}
+	return roots, nil
+}
+
+// topLevelType returns the type t is nested in, or t
+func topLevelType(t *jsonmodel.Type) *jsonmodel.Type {
+	for t.Parent != nil {
+		t = t.Parent
+	}
+	return t
+}
+
+// buildClosure returns the fq names of the root symbols and of the declarations they
+// depend on: the types their signatures, fields, methods and base interfaces refer to,
+// transitively. Roots are functions, types or constants, by name or namespace qualified,
+// a function or struct with a unicode alias can also be named by the alias.
+func buildClosure(apis []*jsonmodel.Api, roots []string, errorValues bool) map[string]bool {
+	closure := make(map[string]bool)
+	var pending []*jsonmodel.Type
+	addType := func(t *jsonmodel.Type) {
+		t = topLevelType(t)
+		if !closure[t.FqName] {
+			closure[t.FqName] = true
+			pending = append(pending, t)
+		}
+	}
+	visit := func(ref *jsonmodel.Type) {
+		if ref.Kind != "ApiRef" {
+			return
+		}
+		if t := ref.GetRefType(); t != nil {
+			addType(t)
+		}
+	}
+	addFoundationType := func(name string) {
+		if t := jsonmodel.TypeRegistry["Foundation."+name]; t != nil {
+			addType(t)
+		}
+	}
+
+	rootSet := make(map[string]bool)
+	for _, it := range roots {
+		rootSet[it] = true
+	}
+	//a root names the W declaration too when the metadata declares the name without W as its alias
+	matches := func(api *jsonmodel.Api, name string) string {
+		alias := ""
+		if strings.HasSuffix(name, "W") {
+			for _, a := range api.UnicodeAliases {
+				if a+"W" == name {
+					alias = a
+					break
+				}
+			}
+		}
+		for _, it := range []string{"", api.Name + "."} {
+			if rootSet[it+name] {
+				return it + name
+			}
+			if alias != "" && rootSet[it+alias] {
+				return it + alias
+			}
+		}
+		return ""
+	}
+	var hasLastError bool
+	for _, api := range apis {
+		for _, t := range api.Types {
+			if root := matches(api, t.Name); root != "" {
+				gMatchedRoots[root] = true
+				addType(t)
+			}
+		}
+		for _, f := range api.Functions {
+			if root := matches(api, f.Name); root != "" {
+				gMatchedRoots[root] = true
+				closure[api.Name+"."+f.Name] = true
+				f.VisitTypeRefs(visit)
+				hasLastError = hasLastError || f.SetLastError
+			}
+		}
+		for _, c := range api.Constants {
+			if root := matches(api, c.Name); root != "" {
+				gMatchedRoots[root] = true
+				closure[api.Name+"."+c.Name] = true
+				c.VisitTypeRefs(visit)
+			}
+		}
+	}
+	//the error types returned in place of the last error and of HRESULTs
+	if hasLastError || errorValues {
+		addFoundationType("WIN32_ERROR")
+	}
+	if errorValues {
+		addFoundationType("HRESULT")
+	}
+	for len(pending) > 0 {
+		t := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		t.VisitTypeRefs(visit)
+		if t.Kind == "ComClassID" {
+			//the interface its creation helper returns
+			if it := classInterface(t); it != nil {
+				addType(it)
+			}
+		}
+	}
+	return closure
+}
+
+// inClosure reports whether the declaration name of namespace ns is generated
+func inClosure(ns string, name string) bool {
+	return gClosure == nil || gClosure[ns+"."+name]
+}
+
+// checkRoots reports the roots that matched no declaration
+func checkRoots(roots []string) {
+	for _, it := range roots {
+		if !gMatchedRoots[it] {
+			diag.Errorf(diag.Location{Name: it}, "the root symbol is not declared")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/utils"
+	"io"
+	"strconv"
+)
+
+// callbackSupported reports whether a function type can be implemented by
+// syscall.NewCallback, which passes every argument and the result in a uintptr
+func callbackSupported(f gomodel.Func) bool {
+	for _, p := range f.Params {
+		if !callbackValueSupported(p.Type) {
+			return false
+		}
+	}
+	return f.ReturnType.Name == "" || !f.ReturnType.IsStruct() &&
+		callbackValueSupported(f.ReturnType)
+}
+
+func callbackValueSupported(ti gomodel.TypeInfo) bool {
+	if ti.IsFloat() || ti.IsFunc() || ti.Name == "string" {
+		return false
+	}
+	return ti.Size.TotalSize <= utils.PtrSize
+}
+
+// callbackParamType returns the type a callback thunk receives an argument of type ti as:
+// pointers as their own type, syscall.NewCallback passes any pointer sized argument, so that
+// they are not converted from uintptr, the other values as uintptr
+func callbackParamType(ti gomodel.TypeInfo) string {
+	if ti.IsPointer() {
+		return ti.Name
+	}
+	return "uintptr"
+}
+
+// callbackArg converts a callback argument received as callbackParamType to its go type
+func callbackArg(name string, ti gomodel.TypeInfo) string {
+	if ti.Name == "uintptr" || ti.IsPointer() {
+		return name
+	} else if ti.Name == "bool" {
+		return name + " != 0"
+	} else if ti.IsStruct() {
+		return "*(*" + ti.Name + ")(unsafe.Pointer(&" + name + "))"
+	}
+	return ti.Name + "(" + name + ")"
+}
+
+// genCallbackConstructor generates NewXxx, which turns a go function of
+// the func type Xxx into a callback pointer that can be passed to win32
+func genCallbackConstructor(f gomodel.Func, w io.Writer) {
+	if !callbackSupported(f) {
+		fmt.Fprint(w, "// ", f.Name, " has arguments syscall.NewCallback cannot pass\n\n")
+		return
+	}
+	slotsName := "callbacksOf" + f.Name
+	fmt.Fprint(w, "var ", slotsName, " = &callbackSlots{newThunk: func(slots *callbackSlots, slot int) uintptr {\n")
+	fmt.Fprint(w, "\treturn syscall.NewCallback(func(")
+	for m, p := range f.Params {
+		if m > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprint(w, "a", m, " ", callbackParamType(p.Type))
+	}
+	fmt.Fprint(w, ") uintptr {\n")
+	fmt.Fprint(w, "\t\tfn := slots.get(slot).(", f.Name, ")\n")
+	fmt.Fprint(w, "\t\t")
+	retType := f.ReturnType
+	if retType.Name != "" {
+		fmt.Fprint(w, "ret := ")
+	}
+	fmt.Fprint(w, "fn(")
+	for m, p := range f.Params {
+		if m > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprint(w, callbackArg("a"+strconv.Itoa(m), p.Type))
+	}
+	fmt.Fprint(w, ")\n")
+	genCallbackReturn(retType, "\t\t", w)
+	fmt.Fprint(w, "\t})\n")
+	fmt.Fprint(w, "}}\n")
+	fmt.Fprintln(w)
+
+	fmt.Fprint(w, "// New", f.Name, " returns a callback pointer calling fn.\n")
+	fmt.Fprint(w, "// Pass it to FreeCallback when it is no longer called, so that it can be reused.\n")
+	fmt.Fprint(w, "func New", f.Name, "(fn ", f.Name, ") uintptr {\n")
+	fmt.Fprint(w, "\treturn ", slotsName, ".alloc(fn)\n")
+	fmt.Fprint(w, "}\n")
+	fmt.Fprintln(w)
+}
+
+// genCallbackReturn returns ret of the given type as the uintptr result of a callback
+func genCallbackReturn(ti gomodel.TypeInfo, indent string, w io.Writer) {
+	if ti.Name == "" {
+		fmt.Fprint(w, indent, "return 0\n")
+	} else if ti.Name == "bool" {
+		fmt.Fprint(w, indent, "if ret {\n", indent, "\treturn 1\n", indent, "}\n")
+		fmt.Fprint(w, indent, "return 0\n")
+	} else if ti.Name == "unsafe.Pointer" {
+		fmt.Fprint(w, indent, "return uintptr(ret)\n")
+	} else if ti.IsPointer() {
+		fmt.Fprint(w, indent, "return uintptr(unsafe.Pointer(ret))\n")
+	} else {
+		fmt.Fprint(w, indent, "return uintptr(ret)\n")
+	}
+}
@@ -9,26 +9,44 @@ import (
 )
 
 func Gen(api *gomodel.GoApi, w io.Writer) {
-	fmt.Fprintln(w, "package win32")
+	if api.BuildTag != "" {
+		fmt.Fprintln(w, "//go:build", api.BuildTag)
+		fmt.Fprintln(w)
+	}
+	pkg := api.Package
+	if pkg == "" {
+		pkg = "win32"
+	}
+	fmt.Fprintln(w, "package", pkg)
 	fmt.Fprintln(w)
 
-	genImports(api, w)
 	genTypeAliases(api, w)
+	genHandles(api, w)
 	genConsts(api, w)
 	genVarConsts(api, w)
 	genEnums(api, w)
+	if api.ErrorValues {
+		genErrorTypes(api, w)
+	}
 	genStructs(api, w)
 	genFuncTypes(api, w)
 
 	genComs(api, w)
+	genComClasses(api, w)
 	genFuncs(api, w)
 }
 
 func libName(dll string) string {
-	return "lib" + utils.CapName(strings.ToLower(dll))
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(strings.TrimSuffix(strings.ToLower(dll), ".dll")))
+	return "lib" + utils.CapName(name)
 }
 
-func genFunc(f gomodel.Func, w io.Writer) {
+func genFunc(api *gomodel.GoApi, f gomodel.Func, w io.Writer) {
 	goName := utils.CapName(f.Name)
 	fmt.Fprint(w, "func ", goName, "(")
 	for m, p := range f.Params {
@@ -55,20 +73,24 @@ func genFunc(f gomodel.Func, w io.Writer) {
 		}
 	}
 
-	if hasRet && f.ReturnError {
-		fmt.Fprint(w, " (", retType, ", WIN32_ERROR)")
-	} else if hasRet {
-		fmt.Fprint(w, " ", retType)
-	} else if f.ReturnError {
-		fmt.Fprint(w, " WIN32_ERROR")
-	}
+	errType := funcErrorType(api)
+	genResults(funcResults(api, f), w)
 
 	fmt.Fprintln(w, " {")
 
+	procName := f.ProcName
+	if procName == "" {
+		procName = f.Name
+	}
 	fmt.Fprint(w, "\t", "addr := lazyAddr(&p", goName,
-		", ", libName(f.Dll), ", \"", f.Name, "\")\n")
+		", ", libName(f.Dll), ", \"", procName, "\")\n")
 
-	if hasRet {
+	//structs that do not fit in a register are returned through a hidden pointer
+	retByPtr := retIsStruct && f.ReturnType.Size.TotalSize > utils.PtrSize
+	if retByPtr {
+		fmt.Fprint(w, "\tvar result ", retType, "\n")
+	}
+	if hasRet && !retByPtr {
 		fmt.Fprint(w, "\tret, _, ")
 	} else {
 		fmt.Fprint(w, "\t_, _, ")
@@ -79,10 +101,13 @@ func genFunc(f gomodel.Func, w io.Writer) {
 		fmt.Fprint(w, " _")
 	}
 	fmt.Fprint(w, " ")
-	if hasRet || f.ReturnError {
+	if hasRet && !retByPtr || f.ReturnError {
 		fmt.Fprint(w, ":")
 	}
 	fmt.Fprint(w, "= syscall.SyscallN(addr")
+	if retByPtr {
+		fmt.Fprint(w, ", uintptr(unsafe.Pointer(&result))")
+	}
 	for _, p := range f.Params {
 		fmt.Fprint(w, ", ")
 		pType := p.Type.Name
@@ -101,7 +126,7 @@ func genFunc(f gomodel.Func, w io.Writer) {
 			continue
 		}
 
-		if p.Type.IsIntPtr() {
+		if p.Type.IsIntPtr() && !p.Type.Defined {
 			fmt.Fprint(w, pName)
 		} else {
 			fmt.Fprint(w, "uintptr(")
@@ -119,32 +144,79 @@ func genFunc(f gomodel.Func, w io.Writer) {
 		}
 	}
 	fmt.Fprintln(w, ")")
+
+	var retExpr string
 	if hasRet {
 		if retType == "uintptr" {
-			fmt.Fprint(w, "\treturn ret")
-		} else if retType == "unsafe.Pointer" {
-			fmt.Fprint(w, "\treturn (", retType, ")(ret)")
+			retExpr = "ret"
 		} else if retIsPtr {
-			fmt.Fprint(w, "\treturn (", retType, ")(unsafe.Pointer(ret))")
+			//pointers are read from ret, converting the uintptr is reported by go vet
+			retExpr = "*(*" + retType + ")(unsafe.Pointer(&ret))"
+		} else if retByPtr {
+			retExpr = "result"
 		} else if retIsStruct {
-			fmt.Fprint(w, "\treturn *(*", retType, ")(unsafe.Pointer(ret))")
+			retExpr = "*(*" + retType + ")(unsafe.Pointer(&ret))"
 		} else {
-			fmt.Fprint(w, "\treturn ", retType, "(ret)")
+			retExpr = retType + "(ret)"
 		}
+	}
+	if returnsError(api, f) {
+		genErrorReturn(f, retExpr, errType, w)
+	} else if hasRet {
+		fmt.Fprint(w, "\treturn ", retExpr)
 		if f.ReturnError {
-			fmt.Fprint(w, ", WIN32_ERROR(err)")
+			fmt.Fprint(w, ", ", lastErrorType(api), "(err)")
 		}
 		fmt.Fprintln(w, "")
 	} else {
 		if f.ReturnError {
-			fmt.Fprint(w, "\treturn WIN32_ERROR(err)\n")
+			fmt.Fprint(w, "\treturn ", lastErrorType(api), "(err)\n")
 		}
 	}
 	fmt.Fprintln(w, "}")
 	fmt.Fprintln(w, "")
 }
 
+// funcErrorType returns the type of the last error returned by SetLastError functions
+func funcErrorType(api *gomodel.GoApi) string {
+	if api.ErrorType == "" {
+		return "WIN32_ERROR"
+	}
+	return api.ErrorType
+}
+
+// funcResults returns the result types of the generated function f
+func funcResults(api *gomodel.GoApi, f gomodel.Func) []string {
+	retType := f.ReturnType.Name
+	if f.ReturnType.IsFunc() {
+		retType = "uintptr"
+	}
+	var results []string
+	if retType != "" && !(returnsError(api, f) && !f.ReturnError) {
+		results = append(results, retType)
+	}
+	if returnsError(api, f) {
+		results = append(results, "error")
+	} else if f.ReturnError {
+		results = append(results, lastErrorType(api))
+	}
+	return results
+}
+
+func genResults(results []string, w io.Writer) {
+	switch len(results) {
+	case 0:
+	case 1:
+		fmt.Fprint(w, " ", results[0])
+	default:
+		fmt.Fprint(w, " (", strings.Join(results, ", "), ")")
+	}
+}
+
 func genFuncs(api *gomodel.GoApi, w io.Writer) {
+	if len(api.Funcs) == 0 {
+		return
+	}
 	fmt.Fprintln(w, "var (")
 
 	aliasMap := make(map[string]string)
@@ -161,8 +233,13 @@ func genFuncs(api *gomodel.GoApi, w io.Writer) {
 	for _, f := range api.Funcs {
 		if a, ok := aliasMap[f.Name]; ok {
 			fmt.Fprintln(w, "var", a, "=", f.Name)
+			if hasWrapper(api, f) {
+				fmt.Fprintln(w, "var", wrapperName(a), "=", wrapperName(f.Name))
+			}
 		}
-		genFunc(f, w)
+		genFunc(api, f, w)
+		genWrapper(api, f, w)
+		genAlloc(api, f, w)
 	}
 	fmt.Fprintln(w)
 }
@@ -174,12 +251,13 @@ func genComs(api *gomodel.GoApi, w io.Writer) {
 	fmt.Fprintln(w, "// coms")
 	fmt.Fprintln(w)
 	for _, it := range api.Coms {
-		genCom(it, w)
+		genCom(api, it, w)
+		genComServer(api, it, w)
 	}
 	fmt.Fprintln(w)
 }
 
-func genCom(c gomodel.Com, w io.Writer) {
+func genCom(api *gomodel.GoApi, c gomodel.Com, w io.Writer) {
 	if c.IID != "" {
 		fmt.Fprintln(w, "// "+c.IID)
 		expr := utils.BuildGuidExpr(c.IID)
@@ -201,6 +279,9 @@ func genCom(c gomodel.Com, w io.Writer) {
 		fmt.Fprint(w, ")")
 
 		retType := method.ReturnType.Name
+		if api.ErrorValues && isHResult(method.ReturnType) {
+			retType = "error"
+		}
 		if retType != "" {
 			fmt.Fprint(w, " ", retType)
 		}
@@ -265,7 +346,11 @@ func genCom(c gomodel.Com, w io.Writer) {
 		var retIsStruct bool
 
 		retType = method.ReturnType.Name
-		if retType != "" {
+		retIsError := api.ErrorValues && isHResult(method.ReturnType)
+		if retIsError {
+			fmt.Fprint(w, " error")
+			hasRet = true
+		} else if retType != "" {
 			fmt.Fprint(w, " ", retType)
 			hasRet = true
 
@@ -274,7 +359,11 @@ func genCom(c gomodel.Com, w io.Writer) {
 		}
 		fmt.Fprintln(w, "{")
 
-		if hasRet {
+		//methods return structs through a hidden pointer following this
+		if retIsStruct {
+			fmt.Fprint(w, "\tvar ret ", retType, "\n")
+			fmt.Fprint(w, "\t_, _, _ ")
+		} else if hasRet {
 			fmt.Fprint(w, "\tret, _, _ :")
 
 		} else {
@@ -282,6 +371,9 @@ func genCom(c gomodel.Com, w io.Writer) {
 		}
 		fmt.Fprint(w, "= syscall.SyscallN(this.Vtbl().",
 			method.Name, ", uintptr(unsafe.Pointer(this))")
+		if retIsStruct {
+			fmt.Fprint(w, ", uintptr(unsafe.Pointer(&ret))")
+		}
 
 		for _, p := range method.Params {
 			fmt.Fprint(w, ", ")
@@ -312,17 +404,16 @@ func genCom(c gomodel.Com, w io.Writer) {
 			}
 		}
 		fmt.Fprintln(w, ")")
-		if hasRet {
+		if retIsError {
+			fmt.Fprint(w, "\tif int32(ret) < 0 {\n")
+			fmt.Fprint(w, "\t\treturn ", retType, "(ret)\n")
+			fmt.Fprint(w, "\t}\n")
+			fmt.Fprint(w, "\treturn nil")
+		} else if hasRet {
 			if retIsPtr {
-				fmt.Fprint(w, "\treturn (", retType, ")(")
-				if retType != "unsafe.Pointer" {
-					fmt.Fprint(w, "unsafe.Pointer(ret)")
-				} else {
-					fmt.Fprint(w, "ret")
-				}
-				fmt.Fprint(w, ")")
+				fmt.Fprint(w, "\treturn *(*", retType, ")(unsafe.Pointer(&ret))")
 			} else if retIsStruct {
-				fmt.Fprint(w, "\treturn *(*", retType, ")(unsafe.Pointer(ret))")
+				fmt.Fprint(w, "\treturn ret")
 			} else if retType == "bool" {
 				fmt.Fprint(w, "\treturn ret != 0")
 			} else {
@@ -356,6 +447,7 @@ func genFuncTypes(api *gomodel.GoApi, w io.Writer) {
 		}
 		fmt.Fprintln(w)
 		fmt.Fprintln(w)
+		genCallbackConstructor(it, w)
 	}
 	fmt.Fprintln(w)
 }
@@ -381,7 +473,7 @@ func genStructs(api *gomodel.GoApi, w io.Writer) {
 			if f.Type.IsFunc() {
 				fType = "uintptr"
 			}
-			if strings.HasPrefix(f.Name, "Anonymous") {
+			if strings.HasPrefix(f.Name, "Anonymous") && fType[0] != '[' {
 				fmt.Fprintln(w, "\t"+fType)
 			} else {
 				fmt.Fprintln(w, "\t"+f.Name, fType)
@@ -455,21 +547,20 @@ func genTypeAliases(api *gomodel.GoApi, w io.Writer) {
 		return
 	}
 	for _, it := range api.TypeAliases {
+		if api.ErrorValues && it.Name == "HRESULT" && HasErrorTypes(api) {
+			//a defined type so that it can implement error
+			fmt.Fprintln(w, "type", it.Name, it.RealName)
+			continue
+		}
+		if it.Defined {
+			fmt.Fprintln(w, "type", it.Name, it.RealName)
+			continue
+		}
 		fmt.Fprintln(w, "type", it.Name, "=", it.RealName)
 	}
 	fmt.Fprintln(w)
 }
 
-func genImports(api *gomodel.GoApi, w io.Writer) {
-	if len(api.Imports) == 0 {
-		return
-	}
-	for _, it := range api.Imports {
-		fmt.Fprintln(w, "import \""+it+"\"")
-	}
-	fmt.Fprintln(w)
-}
-
 func genConsts(api *gomodel.GoApi, w io.Writer) {
 	if len(api.Consts) == 0 {
 		return
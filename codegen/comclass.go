@@ -0,0 +1,68 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/utils"
+	"io"
+)
+
+func genComClasses(api *gomodel.GoApi, w io.Writer) {
+	if len(api.ComClasses) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "// com classes")
+	fmt.Fprintln(w)
+	for _, it := range api.ComClasses {
+		fmt.Fprintln(w, "// "+it.CLSID)
+		fmt.Fprint(w, "var CLSID_", it.Name, " = ", utils.BuildGuidExpr(it.CLSID), "\n")
+		fmt.Fprintln(w)
+		genCoCreate(api, it, w)
+	}
+}
+
+// genCoCreate generates the CoCreateInstance helper of the class c returning its interface,
+// with the HRESULT of the call, an error with -errors
+func genCoCreate(api *gomodel.GoApi, c gomodel.ComClass, w io.Writer) {
+	loc := diag.Location{Name: api.Name + "." + c.Name}
+	f := api.CoCreateInstance
+	if f == nil || len(f.Params) != 5 {
+		diag.Warn(loc, "CoCreate%s is not generated, CoCreateInstance of System.Com is not", c.Name)
+		return
+	}
+	if c.Interface == "" {
+		diag.Warn(loc, "CoCreate%s is not generated, the interface of the class is not", c.Name)
+		return
+	}
+	if baseTypeName(gomodel.TypeInfo{Name: c.Interface}) == "IUnknown" {
+		diag.Warn(loc, "CoCreate%s returns IUnknown, the namespace declares no I%s interface",
+			c.Name, c.Name)
+	}
+	ppv := "unsafe.Pointer(&p)"
+	if ppvType := f.Params[4].Type.Name; ppvType != "unsafe.Pointer" {
+		ppv = "(" + ppvType + ")(" + ppv + ")"
+	}
+	retIsError := api.ErrorValues && isHResult(f.ReturnType)
+	errType := f.ReturnType.Name
+	if retIsError {
+		errType = "error"
+	}
+	fmt.Fprint(w, "// CoCreate", c.Name, " creates an object of the class ", c.Name, " and returns its ",
+		baseTypeName(gomodel.TypeInfo{Name: c.Interface}), " interface\n")
+	fmt.Fprint(w, "func CoCreate", c.Name, "(clsCtx ", f.Params[2].Type.Name, ") (*",
+		c.Interface, ", ", errType, ") {\n")
+	fmt.Fprint(w, "\tvar p *", c.Interface, "\n")
+	call := f.Name + "(&CLSID_" + c.Name + ", nil, clsCtx, &" + c.InterfaceIID + ", " + ppv + ")"
+	if retIsError {
+		fmt.Fprint(w, "\tif err := ", call, "; err != nil {\n")
+		fmt.Fprint(w, "\t\treturn nil, err\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn p, nil\n")
+	} else {
+		//p is nil when the call fails
+		fmt.Fprint(w, "\thr := ", call, "\n")
+		fmt.Fprint(w, "\treturn p, hr\n")
+	}
+	fmt.Fprint(w, "}\n\n")
+}
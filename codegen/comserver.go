@@ -0,0 +1,268 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/gomodel"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// qualifier returns the package qualifier of a go name, including the dot
+func qualifier(name string) string {
+	return name[:strings.LastIndexByte(name, '.')+1]
+}
+
+func lowerFirst(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// comServerBase implements the IUnknown part of all go implemented COM objects,
+// it is generated with IUnknown
+const comServerBase = `// ComServer is a COM object implemented by a go value,
+// it is created by the NewXxxServer functions generated for each interface.
+// The object is kept alive until its reference count drops to zero.
+type ComServer struct {
+	LpVtbl *[1024]uintptr
+	refs   int32
+	impl   interface{}
+	iids   []*syscall.GUID
+}
+
+var (
+	comServerMu sync.Mutex
+	comServers  = make(map[*ComServer]bool)
+)
+
+// NewComServer creates a COM object with the vtable vtbl dispatching to impl,
+// answering QueryInterface for IUnknown and iids. Its reference count starts at 1.
+func NewComServer(vtbl []uintptr, impl interface{}, iids ...*syscall.GUID) *ComServer {
+	server := &ComServer{
+		LpVtbl: (*[1024]uintptr)(unsafe.Pointer(&vtbl[0])),
+		refs:   1,
+		impl:   impl,
+		iids:   iids,
+	}
+	comServerMu.Lock()
+	comServers[server] = true
+	comServerMu.Unlock()
+	return server
+}
+
+// ComServerOf returns the server object of a COM this pointer
+func ComServerOf(this unsafe.Pointer) *ComServer {
+	return (*ComServer)(this)
+}
+
+// Impl returns the go value implementing the object
+func (this *ComServer) Impl() interface{} {
+	return this.impl
+}
+
+func (this *ComServer) AddRef() uint32 {
+	return uint32(atomic.AddInt32(&this.refs, 1))
+}
+
+func (this *ComServer) Release() uint32 {
+	refs := atomic.AddInt32(&this.refs, -1)
+	if refs == 0 {
+		comServerMu.Lock()
+		delete(comServers, this)
+		comServerMu.Unlock()
+	}
+	return uint32(refs)
+}
+
+func (this *ComServer) queryInterface(riid *syscall.GUID, ppvObject *uintptr) uintptr {
+	if *riid == IID_IUnknown {
+		*ppvObject = uintptr(unsafe.Pointer(this))
+		this.AddRef()
+		return 0
+	}
+	for _, iid := range this.iids {
+		if *riid == *iid {
+			*ppvObject = uintptr(unsafe.Pointer(this))
+			this.AddRef()
+			return 0
+		}
+	}
+	*ppvObject = 0
+	return 0x80004002 //E_NOINTERFACE
+}
+
+var (
+	iUnknownServerOnce  sync.Once
+	iUnknownServerThunks []uintptr
+)
+
+// IUnknownServerMethods appends the IUnknown methods of ComServer to a vtable
+func IUnknownServerMethods(vtbl []uintptr) []uintptr {
+	iUnknownServerOnce.Do(func() {
+		iUnknownServerThunks = []uintptr{
+			syscall.NewCallback(func(this unsafe.Pointer, riid *syscall.GUID, ppvObject *uintptr) uintptr {
+				return ComServerOf(this).queryInterface(riid, ppvObject)
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).AddRef())
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).Release())
+			}),
+		}
+	})
+	return append(vtbl, iUnknownServerThunks...)
+}
+
+`
+
+// comMethodServable reports whether a go implementation of a COM method can be called
+// from a syscall.NewCallback thunk
+func comMethodServable(m gomodel.Func) bool {
+	for _, p := range m.Params {
+		if !callbackValueSupported(p.Type) {
+			return false
+		}
+	}
+	return m.ReturnType.Name == "" || !m.ReturnType.IsStruct() &&
+		callbackValueSupported(m.ReturnType)
+}
+
+// genComServerUnknown generates the IUnknown stubs go implementations embed
+// to satisfy IUnknownInterface, the calls are answered by ComServer itself
+func genComServerUnknown(api *gomodel.GoApi, c gomodel.Com, w io.Writer) {
+	fmt.Fprint(w, comServerBase)
+	fmt.Fprintln(w, "// ComServerUnknown is embedded in go implementations of COM interfaces")
+	fmt.Fprintln(w, "// to satisfy IUnknownInterface, a server object answers IUnknown calls itself.")
+	fmt.Fprintln(w, "type ComServerUnknown struct{}")
+	fmt.Fprintln(w)
+	for _, method := range c.Methods {
+		fmt.Fprint(w, "func (ComServerUnknown) ", method.Name, "(")
+		for m, p := range method.Params {
+			if m > 0 {
+				fmt.Fprint(w, ", ")
+			}
+			fmt.Fprint(w, p.Name, " ", p.Type)
+		}
+		fmt.Fprint(w, ")")
+		retType := method.ReturnType.Name
+		if api.ErrorValues && isHResult(method.ReturnType) {
+			retType = "error"
+		}
+		if retType == "" {
+			fmt.Fprint(w, " {\n}\n\n")
+			continue
+		}
+		fmt.Fprint(w, " ", retType, " {\n")
+		fmt.Fprint(w, "\tvar ret ", retType, "\n")
+		fmt.Fprint(w, "\treturn ret\n")
+		fmt.Fprint(w, "}\n\n")
+	}
+}
+
+// genComServer generates the vtable of go implementations of c and their constructor
+func genComServer(api *gomodel.GoApi, c gomodel.Com, w io.Writer) {
+	if c.Super == "" {
+		genComServerUnknown(api, c, w)
+		return
+	}
+	root := qualifier(c.Unknown)
+	interfaceName := c.Name + "Interface"
+
+	fmt.Fprint(w, "// ", c.Name, "ServerMethods appends the methods of ", c.Name,
+		" implemented by the go value of a ComServer to a vtable\n")
+	fmt.Fprint(w, "func ", c.Name, "ServerMethods(vtbl []uintptr) []uintptr {\n")
+	fmt.Fprint(w, "\tvtbl = ", c.Super, "ServerMethods(vtbl)\n")
+	if len(c.Methods) == 0 {
+		fmt.Fprint(w, "\treturn vtbl\n}\n\n")
+	} else {
+		fmt.Fprint(w, "\treturn append(vtbl,\n")
+		for _, method := range c.Methods {
+			genComServerThunk(api, root, interfaceName, method, w)
+		}
+		fmt.Fprint(w, "\t)\n}\n\n")
+	}
+
+	onceName := lowerFirst(c.Name) + "ServerOnce"
+	vtblName := lowerFirst(c.Name) + "ServerVtbl"
+	fmt.Fprint(w, "var (\n")
+	fmt.Fprint(w, "\t", onceName, " sync.Once\n")
+	fmt.Fprint(w, "\t", vtblName, " []uintptr\n")
+	fmt.Fprint(w, ")\n\n")
+
+	var iids []string
+	if c.IID != "" {
+		iids = append(iids, "&IID_"+c.Name)
+	}
+	for _, iid := range c.SuperIIDs {
+		if iid != root+"IID_IUnknown" {
+			iids = append(iids, "&"+iid)
+		}
+	}
+	fmt.Fprint(w, "// New", c.Name, "Server returns a COM object implementing ", c.Name,
+		" by calling impl,\n")
+	fmt.Fprint(w, "// with a reference count of 1. impl usually embeds ", root,
+		"ComServerUnknown.\n")
+	fmt.Fprint(w, "func New", c.Name, "Server(impl ", interfaceName, ") *", c.Name, " {\n")
+	fmt.Fprint(w, "\t", onceName, ".Do(func() {\n")
+	fmt.Fprint(w, "\t\t", vtblName, " = ", c.Name, "ServerMethods(nil)\n")
+	fmt.Fprint(w, "\t})\n")
+	fmt.Fprint(w, "\tserver := ", root, "NewComServer(", vtblName, ", impl")
+	for _, iid := range iids {
+		fmt.Fprint(w, ", ", iid)
+	}
+	fmt.Fprint(w, ")\n")
+	fmt.Fprint(w, "\treturn (*", c.Name, ")(unsafe.Pointer(server))\n")
+	fmt.Fprint(w, "}\n\n")
+}
+
+func genComServerThunk(api *gomodel.GoApi, root string, interfaceName string,
+	method gomodel.Func, w io.Writer) {
+
+	fmt.Fprint(w, "\t\tsyscall.NewCallback(func(this unsafe.Pointer")
+	for m, p := range method.Params {
+		fmt.Fprint(w, ", a", m, " ", callbackParamType(p.Type))
+	}
+	fmt.Fprint(w, ") uintptr {\n")
+	if !comMethodServable(method) {
+		// only HRESULT methods can report they are not implemented,
+		// the others answer the zero value
+		if isHResult(method.ReturnType) {
+			fmt.Fprint(w, "\t\t\treturn 0x80004001 //E_NOTIMPL\n")
+		} else {
+			fmt.Fprint(w, "\t\t\treturn 0\n")
+		}
+		fmt.Fprint(w, "\t\t}),\n")
+		return
+	}
+	fmt.Fprint(w, "\t\t\timpl := ", root, "ComServerOf(this).Impl().(", interfaceName, ")\n")
+	fmt.Fprint(w, "\t\t\t")
+	retIsError := api.ErrorValues && isHResult(method.ReturnType)
+	if retIsError {
+		fmt.Fprint(w, "err := ")
+	} else if method.ReturnType.Name != "" {
+		fmt.Fprint(w, "ret := ")
+	}
+	fmt.Fprint(w, "impl.", method.Name, "(")
+	for m, p := range method.Params {
+		if m > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprint(w, callbackArg("a"+strconv.Itoa(m), p.Type))
+	}
+	fmt.Fprint(w, ")\n")
+	if retIsError {
+		fmt.Fprint(w, "\t\t\tif err == nil {\n\t\t\t\treturn 0\n\t\t\t}\n")
+		// errors wrapping an HRESULT or a WIN32_ERROR are answered as such, others as E_FAIL
+		q := qualifier(method.ReturnType.Name)
+		fmt.Fprint(w, "\t\t\tvar hr ", method.ReturnType.Name, "\n")
+		fmt.Fprint(w, "\t\t\tif errors.As(err, &hr) {\n")
+		fmt.Fprint(w, "\t\t\t\treturn uintptr(hr)\n\t\t\t}\n")
+		fmt.Fprint(w, "\t\t\tvar code ", q, "WIN32_ERROR\n")
+		fmt.Fprint(w, "\t\t\tif errors.As(err, &code) {\n")
+		fmt.Fprint(w, "\t\t\t\treturn uintptr(", q, "HRESULT_FROM_WIN32(code))\n\t\t\t}\n")
+		fmt.Fprint(w, "\t\t\treturn 0x80004005 //E_FAIL\n")
+	} else {
+		genCallbackReturn(method.ReturnType, "\t\t\t", w)
+	}
+	fmt.Fprint(w, "\t\t}),\n")
+}
@@ -0,0 +1,218 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/gomodel"
+	"io"
+	"strings"
+)
+
+func baseTypeName(ti gomodel.TypeInfo) string {
+	name := ti.Name
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+func isHResult(ti gomodel.TypeInfo) bool {
+	return baseTypeName(ti) == "HRESULT"
+}
+
+func isBool(ti gomodel.TypeInfo) bool {
+	name := baseTypeName(ti)
+	return name == "BOOL" || name == "BOOLEAN"
+}
+
+// failureCheck returns the condition on ret under which f failed, "" when its result
+// does not tell: BOOL results are zero, handles and pointers zero or INVALID_HANDLE_VALUE
+// and HRESULTs negative
+func failureCheck(f gomodel.Func) string {
+	switch {
+	case isBool(f.ReturnType):
+		return "ret == 0"
+	case isHResult(f.ReturnType):
+		return "int32(ret) < 0"
+	case f.ReturnType.IsPointer() || f.ReturnType.IsIntPtr():
+		return "ret == 0 || ret == ^uintptr(0)"
+	}
+	return ""
+}
+
+// returnsError reports whether the generated function f returns a go error: HRESULT results
+// are returned as errors, the last error when the result tells that f failed. Other
+// functions succeeding may leave any last error, it is returned raw, see lastErrorType.
+func returnsError(api *gomodel.GoApi, f gomodel.Func) bool {
+	if !api.ErrorValues {
+		return false
+	}
+	if f.ReturnError {
+		return failureCheck(f) != ""
+	}
+	return isHResult(f.ReturnType)
+}
+
+// lastErrorType returns the type of the raw last error of the functions that do not return
+// a go error: WIN32_ERROR, or uint32 with -errors, a WIN32_ERROR would be a non-nil error
+// after the calls that succeed
+func lastErrorType(api *gomodel.GoApi) string {
+	if api.ErrorValues {
+		return "uint32"
+	}
+	return funcErrorType(api)
+}
+
+// genErrorReturn generates the returns of a function returning a go error,
+// see returnsError. A function failing without a last error returns ERROR_GEN_FAILURE.
+func genErrorReturn(f gomodel.Func, retExpr string, errType string, w io.Writer) {
+	if !f.ReturnError {
+		fmt.Fprint(w, "\tif ", failureCheck(f), " {\n")
+		fmt.Fprint(w, "\t\treturn ", retExpr, "\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn nil\n")
+		return
+	}
+	cond := failureCheck(f)
+	if strings.Contains(cond, "||") {
+		cond = "(" + cond + ")"
+	}
+	fmt.Fprint(w, "\tif ", cond, " {\n")
+	fmt.Fprint(w, "\t\tif err == 0 {\n")
+	fmt.Fprint(w, "\t\t\terr = 31 //ERROR_GEN_FAILURE\n")
+	fmt.Fprint(w, "\t\t}\n")
+	fmt.Fprint(w, "\t\treturn ", retExpr, ", ", errType, "(err)\n")
+	fmt.Fprint(w, "\t}\n")
+	fmt.Fprint(w, "\treturn ", retExpr, ", nil\n")
+}
+
+// uniqueNamesByValue returns the first name declared for each value
+func uniqueNamesByValue(names []string, values []string) ([]string, []string) {
+	seen := make(map[string]bool)
+	var uNames, uValues []string
+	for n, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		uNames = append(uNames, names[n])
+		uValues = append(uValues, value)
+	}
+	return uNames, uValues
+}
+
+func genNameMap(mapName string, typeName string,
+	names []string, values []string, w io.Writer) {
+
+	names, values = uniqueNamesByValue(names, values)
+	fmt.Fprint(w, "var ", mapName, " = map[", typeName, "]string{\n")
+	for n, name := range names {
+		fmt.Fprint(w, "\t", values[n], ": \"", name, "\",\n")
+	}
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+}
+
+const win32ErrorMethods = `// Error returns the constant name and the system message of the error code
+func (this WIN32_ERROR) Error() string {
+	msg := syscall.Errno(this).Error()
+	if name, ok := win32ErrorNames[this]; ok {
+		return name + ": " + msg
+	}
+	return msg
+}
+
+// Is reports whether target is the same error code,
+// as a WIN32_ERROR, a syscall.Errno or an HRESULT of FACILITY_WIN32
+func (this WIN32_ERROR) Is(target error) bool {
+	switch t := target.(type) {
+	case WIN32_ERROR:
+		return this == t
+	case syscall.Errno:
+		return uint32(this) == uint32(t)
+	case HRESULT:
+		return this != 0 && HRESULT_FROM_WIN32(this) == t
+	}
+	return false
+}
+
+// HRESULT_FROM_WIN32 returns the HRESULT of FACILITY_WIN32 wrapping code,
+// S_OK for ERROR_SUCCESS
+func HRESULT_FROM_WIN32(code WIN32_ERROR) HRESULT {
+	if int32(code) <= 0 {
+		return HRESULT(code)
+	}
+	return HRESULT(uint32(code)&0xFFFF | 7<<16 | 0x80000000)
+}
+
+`
+
+const hresultMethods = `// Error returns the constant name and the hexadecimal value of the HRESULT
+func (this HRESULT) Error() string {
+	code := "0x" + strconv.FormatUint(uint64(uint32(this)), 16)
+	if name, ok := hresultNames[this]; ok {
+		return name + " (" + code + ")"
+	}
+	if uint32(this)&0xFFFF0000 == 0x80070000 {
+		return WIN32_ERROR(uint32(this)&0xFFFF).Error() + " (" + code + ")"
+	}
+	return "HRESULT " + code
+}
+
+// Is reports whether target is the same HRESULT,
+// or the WIN32_ERROR or syscall.Errno wrapped by an HRESULT of FACILITY_WIN32
+func (this HRESULT) Is(target error) bool {
+	switch t := target.(type) {
+	case HRESULT:
+		return this == t
+	case WIN32_ERROR:
+		return t != 0 && HRESULT_FROM_WIN32(t) == this
+	case syscall.Errno:
+		return t != 0 && HRESULT_FROM_WIN32(WIN32_ERROR(t)) == this
+	}
+	return false
+}
+
+`
+
+// HasErrorTypes reports whether api declares both WIN32_ERROR and HRESULT,
+// which implement error when api.ErrorValues is set
+func HasErrorTypes(api *gomodel.GoApi) bool {
+	var hasWin32Error, hasHResult bool
+	for _, it := range api.Enums {
+		if it.Name == "WIN32_ERROR" {
+			hasWin32Error = true
+		}
+	}
+	for _, it := range api.TypeAliases {
+		if it.Name == "HRESULT" {
+			hasHResult = true
+		}
+	}
+	return hasWin32Error && hasHResult
+}
+
+// genErrorTypes makes WIN32_ERROR and HRESULT implement error
+func genErrorTypes(api *gomodel.GoApi, w io.Writer) {
+	if !HasErrorTypes(api) {
+		return
+	}
+	for _, it := range api.Enums {
+		if it.Name != "WIN32_ERROR" {
+			continue
+		}
+		var names, values []string
+		for _, v := range it.Values {
+			names = append(names, v.Name)
+			values = append(values, v.Value)
+		}
+		genNameMap("win32ErrorNames", it.Name, names, values, w)
+	}
+	fmt.Fprint(w, win32ErrorMethods)
+
+	var names, values []string
+	for _, c := range api.Consts {
+		if c.Type == "HRESULT" {
+			names = append(names, c.Name)
+			values = append(values, c.Value)
+		}
+	}
+	genNameMap("hresultNames", "HRESULT", names, values, w)
+	fmt.Fprint(w, hresultMethods)
+}
@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/gomodel"
+	"io"
+)
+
+// leakSupport reports the handles Owned finds garbage collected without Close,
+// it is generated in runtime.go of packages with Owned handles
+const leakSupport = `
+// HandleLeaked is called with the type name and value of an Owned handle that is
+// garbage collected without being closed. It logs the handle unless it is replaced.
+var HandleLeaked = func(typeName string, handle uintptr) {
+	log.Printf("%s %#x was not closed", typeName, handle)
+}
+`
+
+// genHandles generates the handle types as defined types, with a Close method calling
+// their free function and returning an error, see genClose, and methods converting them
+// to the types they are usable for.
+// With api.LeakCheck handles with a Close method also get Owned, which returns an owner
+// whose finalizer reports the handle to HandleLeaked if it is not closed.
+func genHandles(api *gomodel.GoApi, w io.Writer) {
+	if len(api.Handles) == 0 {
+		return
+	}
+	for _, it := range api.Handles {
+		fmt.Fprintln(w, "type", it.Name, it.BaseType)
+		fmt.Fprintln(w)
+		for _, target := range it.UsableFor {
+			fmt.Fprint(w, "// ", target, " returns the handle as a ", target, "\n")
+			fmt.Fprint(w, "func (this ", it.Name, ") ", target, "() ", target, " {\n")
+			fmt.Fprint(w, "\treturn ", target, "(this)\n")
+			fmt.Fprint(w, "}\n\n")
+		}
+		if it.Free == nil {
+			continue
+		}
+		arg := "this"
+		if pType := it.Free.Params[0].Type.Name; pType != it.Name {
+			arg = pType + "(this)"
+		}
+		fmt.Fprint(w, "// Close closes the handle with ", it.Free.Name,
+			", which returns the results of the call as they are\n")
+		fmt.Fprint(w, "func (this ", it.Name, ") Close() error {\n")
+		genClose(api, *it.Free, it.Free.Name+"("+arg+")", w)
+		fmt.Fprint(w, "}\n\n")
+		if api.LeakCheck {
+			genOwnedHandle(it, w)
+		}
+	}
+}
+
+// genClose generates the body of Close making the results of call, a call of the free
+// function f, an error: the error f returns with -errors, the last error or the result
+// code when f failed, ERROR_GEN_FAILURE when it failed without one, and nil otherwise.
+// Without -errors the codes are returned as syscall.Errno, WIN32_ERROR is no error.
+func genClose(api *gomodel.GoApi, f gomodel.Func, call string, w io.Writer) {
+	errOf := func(code string) string {
+		if api.ErrorValues {
+			return funcErrorType(api) + "(" + code + ")"
+		}
+		return "syscall.Errno(" + code + ")"
+	}
+	hasRet := f.ReturnType.Name != ""
+	//a BOOL or HRESULT result tells that f failed, handle results are not returned by free functions
+	var failed string
+	if isBool(f.ReturnType) {
+		failed = "ret == 0"
+	} else if isHResult(f.ReturnType) {
+		failed = "int32(ret) < 0"
+	}
+	switch {
+	case returnsError(api, f):
+		if hasRet {
+			fmt.Fprint(w, "\t_, err := ", call, "\n")
+			fmt.Fprint(w, "\treturn err\n")
+		} else {
+			fmt.Fprint(w, "\treturn ", call, "\n")
+		}
+	case f.ReturnError && failed != "":
+		fmt.Fprint(w, "\tif ret, lastErr := ", call, "; ", failed, " {\n")
+		fmt.Fprint(w, "\t\tif lastErr == 0 {\n")
+		fmt.Fprint(w, "\t\t\treturn ", errOf("31"), " //ERROR_GEN_FAILURE\n")
+		fmt.Fprint(w, "\t\t}\n")
+		fmt.Fprint(w, "\t\treturn ", errOf("lastErr"), "\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn nil\n")
+	case baseTypeName(f.ReturnType) == "WIN32_ERROR":
+		fmt.Fprint(w, "\tif ret := ", call, "; ret != 0 {\n")
+		fmt.Fprint(w, "\t\treturn ", errOf("ret"), "\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn nil\n")
+	case isHResult(f.ReturnType):
+		fmt.Fprint(w, "\tif ret := ", call, "; ", failed, " {\n")
+		fmt.Fprint(w, "\t\treturn syscall.Errno(uint32(ret))\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn nil\n")
+	case isBool(f.ReturnType):
+		fmt.Fprint(w, "\tif ret := ", call, "; ", failed, " {\n")
+		fmt.Fprint(w, "\t\treturn ", errOf("31"), " //ERROR_GEN_FAILURE\n")
+		fmt.Fprint(w, "\t}\n")
+		fmt.Fprint(w, "\treturn nil\n")
+	default:
+		//the result does not tell whether f failed
+		fmt.Fprint(w, "\t", call, "\n")
+		fmt.Fprint(w, "\treturn nil\n")
+	}
+}
+
+func genOwnedHandle(h gomodel.Handle, w io.Writer) {
+	ownerName := "Owned" + h.Name
+	fmt.Fprint(w, "// ", ownerName, " owns a ", h.Name, ", see ", h.Name, ".Owned\n")
+	fmt.Fprint(w, "type ", ownerName, " struct {\n")
+	fmt.Fprint(w, "\t", h.Name, "\n")
+	fmt.Fprint(w, "}\n\n")
+
+	fmt.Fprint(w, "// Owned returns an owner of the handle that reports it to HandleLeaked\n")
+	fmt.Fprint(w, "// if it is garbage collected before Close is called.\n")
+	fmt.Fprint(w, "func (this ", h.Name, ") Owned() *", ownerName, " {\n")
+	fmt.Fprint(w, "\towner := &", ownerName, "{this}\n")
+	fmt.Fprint(w, "\truntime.SetFinalizer(owner, func(owner *", ownerName, ") {\n")
+	fmt.Fprint(w, "\t\tHandleLeaked(\"", h.Name, "\", uintptr(owner.", h.Name, "))\n")
+	fmt.Fprint(w, "\t})\n")
+	fmt.Fprint(w, "\treturn owner\n")
+	fmt.Fprint(w, "}\n\n")
+
+	fmt.Fprint(w, "// Close closes the handle, it is no longer reported as leaked\n")
+	fmt.Fprint(w, "func (this *", ownerName, ") Close() error {\n")
+	fmt.Fprint(w, "\truntime.SetFinalizer(this, nil)\n")
+	fmt.Fprint(w, "\treturn this.", h.Name, ".Close()\n")
+	fmt.Fprint(w, "}\n\n")
+}
+
+// HasOwnedHandles reports whether api generates Owned handles,
+// which need the HandleLeaked of GenRuntime
+func HasOwnedHandles(api *gomodel.GoApi) bool {
+	if !api.LeakCheck {
+		return false
+	}
+	for _, it := range api.Handles {
+		if it.Free != nil {
+			return true
+		}
+	}
+	return false
+}
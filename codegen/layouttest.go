@@ -0,0 +1,68 @@
+package codegen
+
+import (
+	"fmt"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/utils"
+	"io"
+	"strings"
+)
+
+// fieldSelector returns the name that selects a struct field, "" for padding
+func fieldSelector(f gomodel.StructField) string {
+	if f.Name == "_" {
+		return ""
+	}
+	fType := f.Type.Name
+	if (f.Name == "" || strings.HasPrefix(f.Name, "Anonymous")) &&
+		fType[0] != '[' && !f.Type.IsFunc() {
+		//embedded
+		fType = strings.TrimPrefix(fType, "*")
+		return fType[strings.LastIndexByte(fType, '.')+1:]
+	}
+	return f.Name
+}
+
+func genLayoutAssert(expr string, want int, w io.Writer) {
+	if want == 0 {
+		fmt.Fprint(w, "\t_ = [1]struct{}{}[", expr, "]\n")
+	} else {
+		fmt.Fprint(w, "\t_ = [1]struct{}{}[", expr, "-", want, "]\n")
+	}
+}
+
+// GenLayoutTest generates compile time assertions of the struct layouts of api
+// for the current arch, it reports whether there was any struct to check
+func GenLayoutTest(api *gomodel.GoApi, w io.Writer) bool {
+	pkgName := api.Package
+	if pkgName == "" {
+		pkgName = "win32"
+	}
+	//the namespace dots keep go from reading the arch from the file name
+	fmt.Fprintf(w, "//go:build %s\n\n", utils.CurArch.GoArch)
+	fmt.Fprintf(w, "package %s\n\n", pkgName)
+	fmt.Fprintln(w, "// The size and alignment of the structs and the offsets of their fields")
+	fmt.Fprintln(w, "// as computed by the generator. A differing layout fails to compile,")
+	fmt.Fprintln(w, "// the constant index of its assertion is out of range.")
+	fmt.Fprintln(w)
+
+	checked := false
+	for _, it := range api.Structs {
+		if it.Align == 0 {
+			continue
+		}
+		checked = true
+		value := it.Name + "{}"
+		fmt.Fprint(w, "// ", it.Name, ": size ", it.Size, ", align ", it.Align, "\n")
+		fmt.Fprint(w, "var (\n")
+		genLayoutAssert("unsafe.Sizeof("+value+")", it.Size, w)
+		genLayoutAssert("unsafe.Alignof("+value+")", it.Align, w)
+		for _, f := range it.Fields {
+			if sel := fieldSelector(f); sel != "" {
+				genLayoutAssert("unsafe.Offsetof("+value+"."+sel+")", f.Offset, w)
+			}
+		}
+		fmt.Fprint(w, ")\n\n")
+	}
+	return checked
+}
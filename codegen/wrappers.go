@@ -0,0 +1,548 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/utils"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// wrapperName returns the name of the wrapper of the function name
+func wrapperName(name string) string {
+	return utils.CapName(name) + "Go"
+}
+
+// isOutValue reports whether p points to a single value the function writes,
+// which wrappers return instead of taking a pointer
+func isOutValue(p gomodel.Param) bool {
+	a := p.Attrs
+	return a.Out && !a.In && !a.Sized && !a.BufferSize && !a.Reserved && p.Buffer == nil &&
+		p.Type.IsPointer() && strings.HasPrefix(p.Type.Name, "*")
+}
+
+// hasWrapper reports whether a wrapper is generated for f
+func hasWrapper(api *gomodel.GoApi, f gomodel.Func) bool {
+	if !api.Wrappers {
+		return false
+	}
+	for _, p := range f.Params {
+		if p.Attrs.Reserved || p.InString != gomodel.NotString || isOutValue(p) || p.Buffer != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// zeroValue returns the zero value of a parameter type
+func zeroValue(ti gomodel.TypeInfo) string {
+	if ti.IsPointer() {
+		return "nil"
+	} else if ti.IsStruct() {
+		return ti.Name + "{}"
+	}
+	return "0"
+}
+
+// joinNames joins names as in "a, b and c"
+func joinNames(names []string, conj string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conj + " " + names[len(names)-1]
+}
+
+// genComment generates a comment of text wrapped at 100 columns
+func genComment(text string, w io.Writer) {
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line) > 2 && len(line)+1+len(word) > 100 {
+			fmt.Fprintln(w, line)
+			line = "//"
+		}
+		line += " " + word
+	}
+	fmt.Fprintln(w, line)
+}
+
+// uniqueName returns name suffixed with underscores until it is not taken, and takes it
+func uniqueName(name string, taken map[string]bool) string {
+	for taken[name] {
+		name += "_"
+	}
+	taken[name] = true
+	return name
+}
+
+// funcResultNames returns the names of the results of f in its wrapper
+func funcResultNames(results []string, f gomodel.Func, taken map[string]bool) []string {
+	var names []string
+	for n, r := range results {
+		if r == "error" {
+			names = append(names, uniqueName("err", taken))
+		} else if n == len(results)-1 && f.ReturnError {
+			names = append(names, uniqueName("lastErr", taken))
+		} else {
+			names = append(names, uniqueName("ret", taken))
+		}
+	}
+	return names
+}
+
+type paramRole int
+
+const (
+	//passed through
+	plainParam paramRole = iota
+
+	//left out and passed as zero
+	reservedParam
+
+	//taken as a go string
+	stringParam
+
+	//returned instead of taken
+	outParam
+
+	//taken as a slice or an array pointer
+	bufferParam
+
+	//left out and passed the length of its buffer
+	sizeParam
+
+	//left out, points to the length of its buffer and is returned
+	sizeOutParam
+)
+
+// wrapperParam is a parameter of the wrapped function
+type wrapperParam struct {
+	gomodel.Param
+	role paramRole
+
+	//the buffer a size parameter holds the size of
+	buffer string
+}
+
+func wrapperParams(f gomodel.Func) []wrapperParam {
+	wps := make([]wrapperParam, len(f.Params))
+	for n, p := range f.Params {
+		wps[n].Param = p
+		switch {
+		case p.Attrs.Reserved:
+			wps[n].role = reservedParam
+		case p.InString != gomodel.NotString:
+			wps[n].role = stringParam
+		case p.Buffer != nil:
+			wps[n].role = bufferParam
+		case isOutValue(p):
+			wps[n].role = outParam
+		}
+	}
+	for _, p := range f.Params {
+		if p.Buffer == nil || p.Buffer.SizeParam < 0 {
+			continue
+		}
+		sp := &wps[p.Buffer.SizeParam]
+		sp.buffer = p.Name
+		if sp.Type.IsPointer() {
+			sp.role = sizeOutParam
+		} else {
+			sp.role = sizeParam
+		}
+	}
+	return wps
+}
+
+// bufferType returns the go type wrappers take for a buffer
+func bufferType(b *gomodel.Buffer) string {
+	if b.SizeParam < 0 {
+		return "*[" + strconv.Itoa(b.Count) + "]" + b.ElemType
+	}
+	return "[]" + b.ElemType
+}
+
+// allocName returns the name of the helper growing the buffer of the function name
+func allocName(name string) string {
+	return utils.CapName(name) + "Alloc"
+}
+
+// growBuffer returns the index of the buffer the XxxAlloc helper of f grows,
+// and the error telling it to grow, or -1 if there is no such helper
+func growBuffer(api *gomodel.GoApi, f gomodel.Func) (int, string) {
+	if !api.Wrappers || !f.GrowBuffer {
+		return -1, ""
+	}
+	grow := -1
+	for n, p := range f.Params {
+		if p.Buffer == nil || p.Buffer.SizeParam < 0 || p.Attrs.Reserved {
+			continue
+		}
+		if grow >= 0 {
+			return -1, "it has several buffers"
+		}
+		grow = n
+	}
+	if grow < 0 {
+		return -1, "it has no buffer sized by another parameter"
+	}
+	if baseTypeName(f.ReturnType) != "WIN32_ERROR" && !f.ReturnError {
+		return -1, "it returns no error code"
+	}
+	return grow, ""
+}
+
+// genWrapper generates XxxGo, which calls Xxx with friendlier parameters and results:
+// it takes go strings for the string parameters, converting them to nul terminated strings,
+// takes slices for the buffers and passes their lengths as their sizes,
+// returns the values of the out parameters and passes zero for the reserved parameters.
+// Strings containing a nul character are returned as an error instead of being passed.
+func genWrapper(api *gomodel.GoApi, f gomodel.Func, w io.Writer) {
+	if hasWrapper(api, f) {
+		genWrapperFunc(api, f, -1, w)
+	}
+}
+
+// genAlloc generates XxxAlloc for the functions configured to grow their buffer.
+// It is XxxGo, except that the buffer is allocated, grown and the call repeated
+// while the function fails with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA,
+// to the length the call reports if any, and returned as a string for utf-16 buffers.
+// The buffer is trimmed to the reported length, see reportedLength.
+func genAlloc(api *gomodel.GoApi, f gomodel.Func, w io.Writer) {
+	if !api.Wrappers || !f.GrowBuffer {
+		return
+	}
+	grow, problem := growBuffer(api, f)
+	if grow < 0 {
+		diag.Warn(diag.Location{Name: api.Name + "." + f.Name},
+			"%s is not generated, %s", allocName(f.Name), problem)
+		return
+	}
+	genWrapperFunc(api, f, grow, w)
+}
+
+// reportedLength returns the parameter through which f reports the length of the grown buffer,
+// or nil: its size parameter if it points to the size, else the only out parameter pointing
+// to an integer of the type of the size, e.g. ReturnLength of GetTokenInformation
+func reportedLength(wps []wrapperParam, grown *wrapperParam) *wrapperParam {
+	sp := &wps[grown.Buffer.SizeParam]
+	if sp.role == sizeOutParam {
+		return sp
+	}
+	var found *wrapperParam
+	for n, p := range wps {
+		if p.role != outParam || p.Type.Name != "*"+sp.Type.Name || !p.Type.IsPointer() {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = &wps[n]
+	}
+	return found
+}
+
+// genWrapperFunc generates XxxGo, or XxxAlloc growing the buffer parameter at index grow
+func genWrapperFunc(api *gomodel.GoApi, f gomodel.Func, grow int, w io.Writer) {
+	wps := wrapperParams(f)
+	funcName := wrapperName(f.Name)
+	var grown *wrapperParam
+	if grow >= 0 {
+		funcName = allocName(f.Name)
+		grown = &wps[grow]
+	}
+	isGrown := func(p wrapperParam) bool {
+		return grown != nil && (p.Name == grown.Name || p.buffer == grown.Name)
+	}
+
+	taken := make(map[string]bool)
+	var strs, optionalStrs, outs, slices, arrays, sizes, reserved, optionals []string
+	var resultNames, resultTypes []string
+	if grown != nil {
+		resultNames = append(resultNames, grown.Name)
+		if grown.Buffer.ElemType == "uint16" {
+			resultTypes = append(resultTypes, "string")
+		} else {
+			resultTypes = append(resultTypes, "[]"+grown.Buffer.ElemType)
+		}
+	}
+	for _, p := range wps {
+		taken[p.Name] = true
+		if isGrown(p) {
+			continue
+		}
+		switch p.role {
+		case reservedParam:
+			reserved = append(reserved, p.Name)
+		case stringParam:
+			strs = append(strs, p.Name)
+			if p.Attrs.Optional {
+				optionalStrs = append(optionalStrs, p.Name)
+			}
+		case outParam, sizeOutParam:
+			outs = append(outs, p.Name)
+			resultNames = append(resultNames, p.Name)
+			resultTypes = append(resultTypes, p.Type.Name[1:])
+		case bufferParam:
+			if p.Buffer.SizeParam < 0 {
+				arrays = append(arrays, p.Name)
+			} else {
+				slices = append(slices, p.Name)
+			}
+			if p.Attrs.Optional {
+				optionals = append(optionals, p.Name)
+			}
+		case plainParam:
+			if p.Attrs.Optional && p.Type.IsPointer() {
+				optionals = append(optionals, p.Name)
+			}
+		}
+		if p.role == sizeParam || p.role == sizeOutParam {
+			sizes = append(sizes, "the length of "+p.buffer+" as "+p.Name)
+		}
+	}
+	results := funcResults(api, f)
+	funcNames := funcResultNames(results, f, taken)
+	resultNames = append(resultNames, funcNames...)
+	resultTypes = append(resultTypes, results...)
+	var errName string
+	if len(results) > 0 && results[len(results)-1] == "error" {
+		errName = funcNames[len(funcNames)-1]
+	}
+	if len(strs) > 0 && errName == "" {
+		errName = uniqueName("err", taken)
+		resultNames = append(resultNames, errName)
+		resultTypes = append(resultTypes, "error")
+	}
+
+	goName := utils.CapName(f.Name)
+	var parts []string
+	if grown != nil {
+		parts = append(parts, "returning "+grown.Name+" in a buffer grown while the call fails"+
+			" with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA")
+	}
+	if len(strs) > 0 {
+		parts = append(parts, "taking go strings for "+joinNames(strs, "and"))
+	}
+	if len(slices) > 0 {
+		parts = append(parts, "taking slices for "+joinNames(slices, "and"))
+	}
+	if len(arrays) > 0 {
+		parts = append(parts, "taking array pointers for "+joinNames(arrays, "and"))
+	}
+	if len(sizes) > 0 {
+		parts = append(parts, "passing "+joinNames(sizes, "and"))
+	}
+	if len(outs) > 0 {
+		parts = append(parts, "returning "+joinNames(outs, "and"))
+	}
+	if len(reserved) > 0 {
+		parts = append(parts, "passing zero for the reserved "+joinNames(reserved, "and"))
+	}
+	genComment(funcName+" calls "+goName+" "+strings.Join(parts, ", ")+".", w)
+	if len(optionalStrs) > 0 {
+		genComment("An empty "+joinNames(optionalStrs, "or")+" is passed as null.", w)
+	}
+	if len(optionals) == 1 {
+		genComment(optionals[0]+" is optional and may be nil.", w)
+	} else if len(optionals) > 1 {
+		genComment(joinNames(optionals, "and")+" are optional and may be nil.", w)
+	}
+	fmt.Fprint(w, "func ", funcName, "(")
+	var n int
+	for _, p := range wps {
+		pType := p.Type.Name
+		switch p.role {
+		case reservedParam, outParam, sizeParam, sizeOutParam:
+			continue
+		case stringParam:
+			pType = "string"
+		case bufferParam:
+			if isGrown(p) {
+				continue
+			}
+			pType = bufferType(p.Buffer)
+		default:
+			if p.Type.IsFunc() {
+				pType = "uintptr"
+			}
+		}
+		if n > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		n++
+		fmt.Fprint(w, p.Name, " ", pType)
+	}
+	fmt.Fprint(w, ")")
+	if len(resultNames) > 0 {
+		fmt.Fprint(w, " (")
+		for n, name := range resultNames {
+			if n > 0 {
+				fmt.Fprint(w, ", ")
+			}
+			fmt.Fprint(w, name, " ", resultTypes[n])
+		}
+		fmt.Fprint(w, ")")
+	}
+	fmt.Fprintln(w, " {")
+
+	//the grown buffer is a local slice, its size parameter a local variable if it is a pointer
+	var bufName, lengthName string
+	if grown != nil {
+		bufName = uniqueName(grown.Name+"Buf", taken)
+		fmt.Fprint(w, "\t", bufName, " := make([]", grown.Buffer.ElemType, ", 256)\n")
+		if sp := wps[grown.Buffer.SizeParam]; sp.role == sizeOutParam {
+			fmt.Fprint(w, "\tvar ", sp.Name, " ", sp.Type.Name[1:], "\n")
+		}
+		if p := reportedLength(wps, grown); p != nil {
+			lengthName = p.Name
+		}
+	}
+	bufferOf := func(p wrapperParam) string {
+		if isGrown(p) {
+			return bufName
+		}
+		return p.buffer
+	}
+
+	//statements of the call, repeated when the buffer is grown
+	call := bytes.NewBuffer(nil)
+	indent := "\t"
+	if grown != nil {
+		indent = "\t\t"
+	}
+	var args []string
+	for _, p := range wps {
+		switch p.role {
+		case plainParam:
+			args = append(args, p.Name)
+		case reservedParam:
+			args = append(args, zeroValue(p.Type))
+		case outParam:
+			args = append(args, "&"+p.Name)
+		case sizeParam:
+			args = append(args, p.Type.Name+"(len("+bufferOf(p)+"))")
+		case sizeOutParam:
+			fmt.Fprint(call, indent, p.Name, " = ", p.Type.Name[1:], "(len(", bufferOf(p), "))\n")
+			args = append(args, "&"+p.Name)
+		case bufferParam:
+			if isGrown(p) {
+				ptrExpr := "&" + bufName + "[0]"
+				if p.Type.Name == "unsafe.Pointer" {
+					ptrExpr = "unsafe.Pointer(" + ptrExpr + ")"
+				}
+				args = append(args, ptrExpr)
+				continue
+			}
+			ptrName := uniqueName(p.Name+"Ptr", taken)
+			args = append(args, ptrName)
+			ptrExpr := "&" + p.Name + "[0]"
+			if p.Type.Name == "unsafe.Pointer" {
+				ptrExpr = "unsafe.Pointer(" + ptrExpr + ")"
+			}
+			fmt.Fprint(w, "\tvar ", ptrName, " ", p.Type.Name, "\n")
+			if p.Buffer.SizeParam < 0 {
+				fmt.Fprint(w, "\tif ", p.Name, " != nil {\n")
+			} else {
+				fmt.Fprint(w, "\tif len(", p.Name, ") > 0 {\n")
+			}
+			fmt.Fprint(w, "\t\t", ptrName, " = ", ptrExpr, "\n")
+			fmt.Fprint(w, "\t}\n")
+		case stringParam:
+			convFunc, ptrType := "syscall.UTF16PtrFromString", "*uint16"
+			if p.InString == gomodel.ANSIString {
+				convFunc, ptrType = "syscall.BytePtrFromString", "*byte"
+			}
+			ptrName := uniqueName(p.Name+"Ptr", taken)
+			args = append(args, ptrName)
+			if p.Attrs.Optional {
+				fmt.Fprint(w, "\tvar ", ptrName, " ", ptrType, "\n")
+				fmt.Fprint(w, "\tif ", p.Name, " != \"\" {\n")
+				fmt.Fprint(w, "\t\t", ptrName, ", ", errName, " = ", convFunc, "(", p.Name, ")\n")
+				fmt.Fprint(w, "\t\tif ", errName, " != nil {\n")
+				fmt.Fprint(w, "\t\t\treturn\n")
+				fmt.Fprint(w, "\t\t}\n")
+				fmt.Fprint(w, "\t}\n")
+			} else {
+				fmt.Fprint(w, "\t", ptrName, ", ", errName, " := ", convFunc, "(", p.Name, ")\n")
+				fmt.Fprint(w, "\tif ", errName, " != nil {\n")
+				fmt.Fprint(w, "\t\treturn\n")
+				fmt.Fprint(w, "\t}\n")
+			}
+		}
+	}
+	fmt.Fprint(call, indent)
+	if len(funcNames) > 0 {
+		fmt.Fprint(call, strings.Join(funcNames, ", "), " = ")
+	}
+	fmt.Fprint(call, goName, "(", strings.Join(args, ", "), ")\n")
+
+	if grown == nil {
+		w.Write(call.Bytes())
+	} else {
+		fmt.Fprintln(w, "\tfor {")
+		w.Write(call.Bytes())
+		fmt.Fprint(w, "\t\tif ", growCondition(api, f, funcNames, bufName), " {\n")
+		fmt.Fprint(w, "\t\t\tbreak\n")
+		fmt.Fprint(w, "\t\t}\n")
+		//the length the call asks for, or twice the length when it does not tell
+		lenName := uniqueName("n", taken)
+		fmt.Fprint(w, "\t\t", lenName, " := 2 * len(", bufName, ")\n")
+		if lengthName != "" {
+			fmt.Fprint(w, "\t\tif int(", lengthName, ") > len(", bufName, ") {\n")
+			fmt.Fprint(w, "\t\t\t", lenName, " = int(", lengthName, ")\n")
+			fmt.Fprint(w, "\t\t}\n")
+		}
+		fmt.Fprint(w, "\t\t", bufName, " = make([]", grown.Buffer.ElemType, ", ", lenName, ")\n")
+		fmt.Fprint(w, "\t}\n")
+		if lengthName != "" {
+			fmt.Fprint(w, "\tif int(", lengthName, ") < len(", bufName, ") {\n")
+			fmt.Fprint(w, "\t\t", bufName, " = ", bufName, "[:", lengthName, "]\n")
+			fmt.Fprint(w, "\t}\n")
+		}
+		if grown.Buffer.ElemType == "uint16" {
+			fmt.Fprint(w, "\t", grown.Name, " = syscall.UTF16ToString(", bufName, ")\n")
+		} else {
+			fmt.Fprint(w, "\t", grown.Name, " = ", bufName, "\n")
+		}
+	}
+	if len(resultNames) > 0 {
+		fmt.Fprintln(w, "\treturn")
+	}
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+}
+
+// growCondition returns the condition under which XxxAlloc stops growing the buffer:
+// the error is neither ERROR_INSUFFICIENT_BUFFER nor ERROR_MORE_DATA, or,
+// for a last error, the result of the function tells that it did not fail
+func growCondition(api *gomodel.GoApi, f gomodel.Func, funcNames []string, bufName string) string {
+	var errExpr, errType, okExpr string
+	var rawLastError bool
+	if baseTypeName(f.ReturnType) == "WIN32_ERROR" {
+		errExpr, errType = funcNames[0], f.ReturnType.Name
+	} else {
+		errExpr, errType = funcNames[len(funcNames)-1], funcErrorType(api)
+		rawLastError = !returnsError(api, f) && lastErrorType(api) != errType
+		if len(funcNames) > 1 {
+			ret := funcNames[0]
+			if isBool(f.ReturnType) {
+				okExpr = ret + " != 0"
+			} else if !f.ReturnType.IsPointer() && !f.ReturnType.IsStruct() && !f.ReturnType.IsFloat() {
+				okExpr = ret + " != 0 && int(" + ret + ") < len(" + bufName + ")"
+			}
+		}
+	}
+	q := qualifier(errType)
+	insufficientBuffer, moreData := q+"ERROR_INSUFFICIENT_BUFFER", q+"ERROR_MORE_DATA"
+	if rawLastError {
+		insufficientBuffer, moreData = "uint32("+insufficientBuffer+")", "uint32("+moreData+")"
+	}
+	cond := errExpr + " != " + insufficientBuffer + " && " + errExpr + " != " + moreData
+	if okExpr != "" {
+		return okExpr + " || (" + cond + ")"
+	}
+	return cond
+}
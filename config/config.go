@@ -0,0 +1,168 @@
+package config
+
+import (
+	_ "embed"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default.yaml
+var defaultYaml []byte
+
+type Size struct {
+	Size  int `yaml:"size"`
+	Align int `yaml:"align"`
+}
+
+type Skip struct {
+	Types         []string `yaml:"types"`
+	Functions     []string `yaml:"functions"`
+	Constants     []string `yaml:"constants"`
+	ConstantTypes []string `yaml:"constantTypes"`
+}
+
+// TypeChecks lists the referenced types the jsonmodel type checks answer for without resolving them
+type TypeChecks struct {
+	IntPointers         []string `yaml:"intPointers"`
+	Unsigned            []string `yaml:"unsigned"`
+	NotFunctionPointers []string `yaml:"notFunctionPointers"`
+	NotStructs          []string `yaml:"notStructs"`
+}
+
+type Config struct {
+	Dlls          []string          `yaml:"dlls"`
+	TypeOverrides map[string]string `yaml:"typeOverrides"`
+	Renames       map[string]string `yaml:"renames"`
+	TypeChecks    TypeChecks        `yaml:"typeChecks"`
+	Sizes         map[string]Size   `yaml:"sizes"`
+	Skip          Skip              `yaml:"skip"`
+	GrowBuffers   []string          `yaml:"growBuffers"`
+
+	dllSet    map[string]bool
+	growSet   map[string]bool
+	skipSets  [4]map[string]bool
+	checkSets [4]map[string]bool
+}
+
+// Cur is the configuration in effect, the built-in one unless Load is called
+var Cur = Default()
+
+// Default returns the built-in configuration
+func Default() *Config {
+	var c Config
+	err := yaml.Unmarshal(defaultYaml, &c)
+	if err != nil {
+		panic(err)
+	}
+	c.init()
+	return &c
+}
+
+// Load reads a yaml or json file and applies it on top of the built-in configuration
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Default()
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	return c, nil
+}
+
+func toSet(names []string, lower bool) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		if lower {
+			name = strings.ToLower(name)
+		}
+		set[name] = true
+	}
+	return set
+}
+
+func (this *Config) init() {
+	this.dllSet = toSet(this.Dlls, true)
+	this.growSet = toSet(this.GrowBuffers, false)
+	this.skipSets = [4]map[string]bool{
+		toSet(this.Skip.Types, false),
+		toSet(this.Skip.Functions, false),
+		toSet(this.Skip.Constants, false),
+		toSet(this.Skip.ConstantTypes, false),
+	}
+	this.checkSets = [4]map[string]bool{
+		toSet(this.TypeChecks.IntPointers, false),
+		toSet(this.TypeChecks.Unsigned, false),
+		toSet(this.TypeChecks.NotFunctionPointers, false),
+		toSet(this.TypeChecks.NotStructs, false),
+	}
+}
+
+func (this *Config) AllowDll(dll string) bool {
+	if len(this.dllSet) == 0 {
+		return true
+	}
+	return this.dllSet[strings.ToLower(dll)]
+}
+
+// TypeOverride returns the go type configured for a referenced metadata type
+func (this *Config) TypeOverride(name string) (string, bool) {
+	goType, ok := this.TypeOverrides[name]
+	return goType, ok
+}
+
+// GoName applies the configured rename of a metadata name, if any
+func (this *Config) GoName(name string, defaultGoName string) string {
+	if goName, ok := this.Renames[name]; ok {
+		return goName
+	}
+	return defaultGoName
+}
+
+// IsIntPointer reports whether the type is a pointer sized integer, e.g. a handle
+func (this *Config) IsIntPointer(name string) bool {
+	return this.checkSets[0][name]
+}
+
+func (this *Config) IsUnsigned(name string) bool {
+	return this.checkSets[1][name]
+}
+
+func (this *Config) IsNotFunctionPointer(name string) bool {
+	return this.checkSets[2][name]
+}
+
+func (this *Config) IsNotStruct(name string) bool {
+	return this.checkSets[3][name]
+}
+
+// GrowBuffer reports whether the wrappers of a function get a helper growing its buffer
+func (this *Config) GrowBuffer(name string) bool {
+	return this.growSet[name]
+}
+
+func (this *Config) ForcedSize(name string) (Size, bool) {
+	size, ok := this.Sizes[name]
+	return size, ok
+}
+
+func (this *Config) SkipType(name string) bool {
+	return this.skipSets[0][name]
+}
+
+func (this *Config) SkipFunction(name string) bool {
+	return this.skipSets[1][name]
+}
+
+func (this *Config) SkipConstant(name string) bool {
+	return this.skipSets[2][name]
+}
+
+func (this *Config) SkipConstantType(name string) bool {
+	return this.skipSets[3][name]
+}
@@ -0,0 +1,174 @@
+// Package diag collects the problems found in the metadata during generation,
+// so that generation continues past them and reports them all at the end.
+package diag
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Location identifies the metadata a diagnostic is about, empty parts are unknown
+type Location struct {
+	//api file
+	File string
+
+	//json path in the file, e.g. Types[12].Fields[3].Type
+	Path string
+
+	//fully qualified name of the type or function
+	Name string
+}
+
+func (l Location) String() string {
+	var parts []string
+	if l.File != "" {
+		parts = append(parts, l.File)
+	}
+	if l.Path != "" {
+		parts = append(parts, l.Path)
+	}
+	if l.Name != "" {
+		parts = append(parts, l.Name)
+	}
+	return strings.Join(parts, ": ")
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Location Location
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if loc := d.Location.String(); loc != "" {
+		return loc + ": " + d.Severity.String() + ": " + d.Message
+	}
+	return d.Severity.String() + ": " + d.Message
+}
+
+// Output receives the diagnostics as they are reported
+var Output io.Writer = os.Stderr
+
+var (
+	diags   []Diagnostic
+	seen    = make(map[string]bool)
+	current Location
+)
+
+// Reset forgets the reported diagnostics
+func Reset() {
+	diags = nil
+	seen = make(map[string]bool)
+	current = Location{}
+}
+
+// report records a diagnostic once, the unknown parts of its location
+// are those of the declaration being guarded
+func report(severity Severity, loc Location, format string, args ...interface{}) {
+	if loc.File == "" {
+		loc.File = current.File
+		if loc.Path == "" {
+			loc.Path = current.Path
+		}
+	}
+	if loc.Name == "" {
+		loc.Name = current.Name
+	}
+	d := Diagnostic{Severity: severity, Location: loc, Message: fmt.Sprintf(format, args...)}
+	key := d.String()
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+	diags = append(diags, d)
+	fmt.Fprintln(Output, key)
+}
+
+func Warn(loc Location, format string, args ...interface{}) {
+	report(Warning, loc, format, args...)
+}
+
+func Errorf(loc Location, format string, args ...interface{}) {
+	report(Error, loc, format, args...)
+}
+
+// failure is the panic value that aborts the declaration being guarded
+type failure struct {
+	loc Location
+	msg string
+}
+
+// Failure returns a panic value that aborts the declaration being guarded
+// with an error at loc:
+//
+//	panic(diag.Failure(t.Location(), "unknown type kind %s", t.Kind))
+func Failure(loc Location, format string, args ...interface{}) interface{} {
+	return failure{loc: loc, msg: fmt.Sprintf(format, args...)}
+}
+
+// Guard runs fn for the declaration at loc. If fn fails, the failure is reported
+// as an error and Guard returns false, so that the caller skips the declaration.
+// Other panics are reported as internal errors with their stack.
+func Guard(loc Location, fn func()) (ok bool) {
+	outer := current
+	current = loc
+	defer func() {
+		if r := recover(); r != nil {
+			if f, isFailure := r.(failure); isFailure {
+				Errorf(f.loc, "%s", f.msg)
+			} else {
+				//a bug of the generator, the stack tells where
+				Errorf(Location{}, "internal error: %v\n%s", r, debug.Stack())
+			}
+			ok = false
+		}
+		current = outer
+	}()
+	fn()
+	return true
+}
+
+// All returns the reported diagnostics
+func All() []Diagnostic {
+	return diags
+}
+
+func count(severity Severity) int {
+	n := 0
+	for _, d := range diags {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func HasErrors() bool {
+	return count(Error) > 0
+}
+
+// PrintSummary prints the number of errors and warnings, if any
+func PrintSummary(w io.Writer) {
+	errors, warnings := count(Error), count(Warning)
+	if errors+warnings == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", errors, warnings)
+}
@@ -0,0 +1,60 @@
+package diag
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	Reset()
+	out := bytes.NewBuffer(nil)
+	Output = out
+	defer func() {
+		Output = os.Stderr
+		Reset()
+	}()
+
+	loc := Location{File: "a.json", Path: "Types[1]", Name: "A.T"}
+	ok := Guard(loc, func() {
+		Warn(Location{}, "w")
+		panic(Failure(Location{Path: "Types[1].Fields[0].Type"}, "bad %s", "field"))
+	})
+	if ok {
+		t.Fatal("Guard returned true for a failed declaration")
+	}
+	if !Guard(loc, func() {}) {
+		t.Fatal("Guard returned false for a declaration without failures")
+	}
+	Guard(loc, func() {
+		panic("boom")
+	})
+	Warn(Location{}, "outside")
+	Warn(Location{}, "outside")
+
+	want := []string{
+		"a.json: Types[1]: A.T: warning: w",
+		"a.json: Types[1].Fields[0].Type: A.T: error: bad field",
+		"a.json: Types[1]: A.T: error: internal error: boom",
+		"warning: outside",
+	}
+	//the internal error is followed by the stack of the panic
+	var got []string
+	for _, d := range All() {
+		got = append(got, strings.SplitN(d.String(), "\n", 2)[0])
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !HasErrors() || len(All()) != 4 {
+		t.Fatalf("got %d diagnostics", len(All()))
+	}
+	if stack := All()[2].Message; !strings.Contains(stack, "diag.TestGuard") {
+		t.Errorf("the internal error has no stack:\n%s", stack)
+	}
+	if !strings.HasPrefix(out.String(), strings.Join(want[:3], "\n")) ||
+		!strings.HasSuffix(out.String(), want[3]+"\n") {
+		t.Errorf("got output\n%s", out.String())
+	}
+}
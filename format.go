@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// stdImports are the standard packages generated code uses, by package name
+var stdImports = map[string]string{
+	"atomic":  "sync/atomic",
+	"errors":  "errors",
+	"runtime": "runtime",
+	"strconv": "strconv",
+	"sync":    "sync",
+	"syscall": "syscall",
+	"unsafe":  "unsafe",
+}
+
+// generatedHeader is the first line of the generated files, see https://go.dev/s/generatedcode
+const generatedHeader = "// Code generated by go-win32api-gen. DO NOT EDIT."
+
+// writeGoFile adds the generated header and the imports code uses, formats and writes it to filePath.
+// Code that does not parse is written as is, so that the reported line can be inspected.
+func writeGoFile(filePath string, code []byte) error {
+	code = append([]byte(generatedHeader+"\n\n"), code...)
+	formatted, err := formatGoFile(filePath, code)
+	if err != nil {
+		_ = ioutil.WriteFile(filePath, code, 0644)
+		return fmt.Errorf("generated code does not parse: %v", err)
+	}
+	return ioutil.WriteFile(filePath, formatted, 0644)
+}
+
+// formatGoFile inserts the imports used by code after its package clause and formats it,
+// errors are reported with positions in filePath
+func formatGoFile(filePath string, code []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, code, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	stdPaths, pkgPaths := usedImports(file)
+
+	offset := fset.Position(file.Name.End()).Offset
+	w := bytes.NewBuffer(nil)
+	w.Write(code[:offset])
+	if len(stdPaths)+len(pkgPaths) > 0 {
+		fmt.Fprint(w, "\n\nimport (\n")
+		for _, it := range stdPaths {
+			fmt.Fprint(w, "\t", strconv.Quote(it), "\n")
+		}
+		if len(stdPaths) > 0 && len(pkgPaths) > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, it := range pkgPaths {
+			fmt.Fprint(w, "\t", strconv.Quote(it), "\n")
+		}
+		fmt.Fprint(w, ")")
+	}
+	w.Write(code[offset:])
+	return format.Source(w.Bytes())
+}
+
+// usedImports returns the standard and generated packages referenced by file
+// that it does not import yet
+func usedImports(file *ast.File) (stdPaths []string, pkgPaths []string) {
+	pkgMap := make(map[string]string)
+	for _, pkg := range gPackages {
+		pkgMap[pkg.Name] = pkg.ImportPath
+	}
+	imported := make(map[string]bool)
+	for _, it := range file.Imports {
+		path, _ := strconv.Unquote(it.Path.Value)
+		imported[path] = true
+	}
+	stdSet := make(map[string]bool)
+	pkgSet := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		//package names are not resolved by the parser
+		x, ok := sel.X.(*ast.Ident)
+		if !ok || x.Obj != nil || x.Name == file.Name.Name {
+			return true
+		}
+		if path, ok := stdImports[x.Name]; ok && !imported[path] {
+			stdSet[path] = true
+		} else if path, ok := pkgMap[x.Name]; ok && !imported[path] {
+			pkgSet[path] = true
+		}
+		return true
+	})
+	for it := range stdSet {
+		stdPaths = append(stdPaths, it)
+	}
+	for it := range pkgSet {
+		pkgPaths = append(pkgPaths, it)
+	}
+	sort.Strings(stdPaths)
+	sort.Strings(pkgPaths)
+	return stdPaths, pkgPaths
+}
@@ -3,10 +3,25 @@ package gomodel
 type GoApi struct {
 	Name string
 
-	Imports []string
+	Package string
+
+	BuildTag string
+
+	ErrorType string
+
+	//return go errors from SetLastError functions and HRESULT methods
+	ErrorValues bool
+
+	//generate idiomatic wrappers of the functions, see codegen.genWrapper
+	Wrappers bool
+
+	//generate Owned handles reporting those that are not closed, see codegen.genHandles
+	LeakCheck bool
 
 	TypeAliases []Alias
 
+	Handles []Handle
+
 	Consts []Const
 
 	VarConsts []Const
@@ -25,5 +40,8 @@ type GoApi struct {
 
 	Coms []Com
 
-	//ComClassID?
+	ComClasses []ComClass
+
+	//CoCreateInstance as seen from this package, nil if it is not generated
+	CoCreateInstance *Func
 }
@@ -1,9 +1,59 @@
 package gomodel
 
+type StringKind int
+
+const (
+	NotString StringKind = iota
+
+	//nul terminated utf-16 string, PWSTR
+	UTF16String
+
+	//nul terminated ansi string, PSTR
+	ANSIString
+)
+
+// ParamAttrs are the metadata attributes of a parameter
+type ParamAttrs struct {
+	In    bool
+	Out   bool
+	Const bool
+
+	//null may be passed for the parameter
+	Optional bool
+
+	//the parameter is reserved and must be zero
+	Reserved bool
+
+	//the parameter points to a buffer whose size in bytes is given by another parameter
+	Sized bool
+
+	//the parameter is the size of a buffer parameter, or points to it
+	BufferSize bool
+}
+
+// Buffer describes a parameter pointing to the first element of a buffer,
+// wrappers take a slice for it
+type Buffer struct {
+	//type of the elements
+	ElemType string
+
+	//index of the parameter holding the size, -1 if the size is constant
+	SizeParam int
+
+	//number of elements of a constant size buffer
+	Count int
+}
+
 type Param struct {
 	Name  string
 	Type  TypeInfo
-	Attrs string
+	Attrs ParamAttrs
+
+	//the parameter is a string read by the function, wrappers take a go string for it
+	InString StringKind
+
+	//nil unless the parameter is a buffer wrappers take a slice for
+	Buffer *Buffer
 }
 
 type Func struct {
@@ -12,4 +62,8 @@ type Func struct {
 	ReturnType  TypeInfo
 	ReturnError bool
 	Dll         string
+	ProcName    string
+
+	//wrappers get an XxxAlloc helper growing the buffer parameter
+	GrowBuffer bool
 }
@@ -10,12 +10,16 @@ const (
 	TypeKindIntPtr  TypeKind = 2
 	TypeKindStruct  TypeKind = 3
 	TypeKindFunc    TypeKind = 4
+	TypeKindFloat   TypeKind = 5
 )
 
 type TypeInfo struct {
 	Name string
 	Kind TypeKind
 	Size utils.SizeInfo
+
+	//the type is a defined type rather than an alias of uintptr, it is converted to pass it
+	Defined bool
 }
 
 func NewTypeInfo(name string) TypeInfo {
@@ -46,9 +50,29 @@ func (me TypeInfo) IsFunc() bool {
 	return me.Kind == TypeKindFunc
 }
 
+func (me TypeInfo) IsFloat() bool {
+	return me.Kind == TypeKindFloat
+}
+
 type Alias struct {
 	Name     string
 	RealName string
+
+	//a type declared as a defined type of RealName rather than an alias
+	Defined bool
+}
+
+// Handle is a handle type declared as a defined type rather than an alias,
+// so that it can have methods
+type Handle struct {
+	Name     string
+	BaseType string
+
+	//function closing the handle, nil if Close is not generated
+	Free *Func
+
+	//handle types the handle can be converted to
+	UsableFor []string
 }
 
 type EnumValue struct {
@@ -66,6 +90,9 @@ type Enum struct {
 type StructField struct {
 	Name string
 	Type TypeInfo
+
+	//offset in the C layout
+	Offset int
 }
 
 type UnionField struct {
@@ -78,6 +105,10 @@ type Struct struct {
 	Fields []StructField
 
 	UnionFields []UnionField
+
+	//size in the C layout and alignment of the go declaration, 0 if the layout is unknown
+	Size  int
+	Align int
 }
 
 type Com struct {
@@ -86,4 +117,20 @@ type Com struct {
 
 	Super   string
 	Methods []Func
+
+	//IID variables of the super interfaces that have one
+	SuperIIDs []string
+
+	//the root interface, IUnknown
+	Unknown string
+}
+
+type ComClass struct {
+	Name  string
+	CLSID string
+
+	//the interface the creation helper returns and its IID variable,
+	//"" when it is not generated
+	Interface    string
+	InterfaceIID string
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go-win32api-gen/config"
+	"go-win32api-gen/gomodel"
+	"go-win32api-gen/jsonmodel"
+	"go-win32api-gen/utils"
+)
+
+// declare handle types as defined types with methods, see gomodel.Handle
+var gHandles bool
+
+// generate Owned handles reporting leaks, see gomodel.GoApi.LeakCheck
+var gLeakCheck bool
+
+// fq names of the handle types other handle types can be converted to
+var gUsableForTargets map[string]bool
+
+// generated functions by name, and the namespaces they are generated in
+var gFreeFuncs map[string]*jsonmodel.Function
+var gFreeFuncNs map[string]string
+
+// collectHandleInfo collects the conversion targets of the handle types
+// and the functions that are generated, which can close them
+func collectHandleInfo(apis []*jsonmodel.Api, filter *nsFilter) {
+	gUsableForTargets = make(map[string]bool)
+	gFreeFuncs = make(map[string]*jsonmodel.Function)
+	gFreeFuncNs = make(map[string]string)
+	for _, api := range apis {
+		for _, t := range api.Types {
+			if t.Kind == "NativeTypedef" && t.AlsoUsableFor != "" {
+				gUsableForTargets[api.Name+"."+t.AlsoUsableFor] = true
+			}
+		}
+		if !filter.Match(api.Name) {
+			continue
+		}
+		for _, f := range api.Functions {
+			if !config.Cur.SkipFunction(f.Name) && inClosure(api.Name, f.Name) {
+				gFreeFuncs[f.Name] = f
+				gFreeFuncNs[f.Name] = api.Name
+			}
+		}
+	}
+}
+
+// isHandle reports whether t is declared as a handle type: a pointer sized native typedef
+// with a free function, or that can be converted to or from another handle type
+func isHandle(t *jsonmodel.Type) bool {
+	if !gHandles || t == nil || t.Kind != "NativeTypedef" || !t.Def.IsIntPointer() {
+		return false
+	}
+	return t.FreeFunc != "" || t.AlsoUsableFor != "" || gUsableForTargets[t.FqName]
+}
+
+// buildHandle builds the handle type t of api. Close is generated when the free function
+// takes only the handle and is generated in the same package, conversions when the
+// target type is declared in the same namespace and generated
+func buildHandle(api *jsonmodel.Api, t *jsonmodel.Type, goApi *gomodel.GoApi) gomodel.Handle {
+	h := gomodel.Handle{
+		Name:     goName(t.Name),
+		BaseType: MapGoTypeInfo(t.Def).Name,
+	}
+	target := jsonmodel.TypeRegistry[api.Name+"."+t.AlsoUsableFor]
+	if target != nil && isHandle(target) && inClosure(api.Name, target.Name) {
+		h.UsableFor = append(h.UsableFor, goName(target.Name))
+	}
+	f, ok := gFreeFuncs[t.FreeFunc]
+	if !ok || len(f.Params) != 1 || gPackages[gFreeFuncNs[f.Name]] != gCurPackage {
+		return h
+	}
+	pt := MapGoTypeInfo(f.Params[0].Type)
+	if !pt.IsIntPtr() {
+		return h
+	}
+	free := &gomodel.Func{
+		Name:        goName(f.Name),
+		ReturnError: f.SetLastError,
+		Params: []gomodel.Param{{
+			Name: utils.SafeGoName(f.Params[0].Name),
+			Type: pt,
+		}},
+	}
+	if f.ReturnType != nil {
+		free.ReturnType = MapGoTypeInfo(f.ReturnType)
+	}
+	if f.SetLastError {
+		goApi.ErrorType = qualifyName("Foundation", "WIN32_ERROR")
+	}
+	h.Free = free
+	return h
+}
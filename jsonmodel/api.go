@@ -2,9 +2,66 @@ package jsonmodel
 
 type Api struct {
 	Name string
+	File string `json:"-"`
 
 	Constants      []*Constant
 	Types          []*Type
 	Functions      []*Function
 	UnicodeAliases []string
 }
+
+// VisitTypeRefs calls fn for every type reference in the api,
+// including the references nested in pointer, array and typedef types
+func (this *Api) VisitTypeRefs(fn func(t *Type)) {
+	for _, c := range this.Constants {
+		c.VisitTypeRefs(fn)
+	}
+	for _, t := range this.Types {
+		t.VisitTypeRefs(fn)
+	}
+	for _, f := range this.Functions {
+		f.VisitTypeRefs(fn)
+	}
+}
+
+func visitTypeRef(t *Type, fn func(t *Type)) {
+	if t == nil {
+		return
+	}
+	fn(t)
+	visitTypeRef(t.Child, fn)
+	visitTypeRef(t.Def, fn)
+}
+
+// VisitTypeRefs calls fn for every type reference in the declaration of t
+// and of its nested types
+func (t *Type) VisitTypeRefs(fn func(t *Type)) {
+	visitTypeRef(t.Def, fn)
+	visitTypeRef(t.Interface, fn)
+	visitTypeRef(t.ReturnType, fn)
+	for _, f := range t.Fields {
+		visitTypeRef(f.Type, fn)
+	}
+	for _, p := range t.Params {
+		visitTypeRef(p.Type, fn)
+	}
+	for _, m := range t.Methods {
+		m.VisitTypeRefs(fn)
+	}
+	for _, nt := range t.NestedTypes {
+		nt.VisitTypeRefs(fn)
+	}
+}
+
+// VisitTypeRefs calls fn for every type reference in the type of c
+func (c *Constant) VisitTypeRefs(fn func(t *Type)) {
+	visitTypeRef(c.Type, fn)
+}
+
+// VisitTypeRefs calls fn for every type reference in the signature of f
+func (f *Function) VisitTypeRefs(fn func(t *Type)) {
+	visitTypeRef(f.ReturnType, fn)
+	for _, p := range f.Params {
+		visitTypeRef(p.Type, fn)
+	}
+}
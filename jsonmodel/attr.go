@@ -16,7 +16,7 @@ func (this *Attr) UnmarshalJSON(p []byte) error {
 		props := make(map[string]interface{})
 		e := json.Unmarshal(p, &props)
 		if e != nil {
-			panic(e)
+			return e
 		}
 		this.Props = props
 	} else {
@@ -40,13 +40,12 @@ func (this *Attr) String() string {
 	return s
 }
 
-func BuildAttrsStr(attrs []Attr) string {
-	s := ""
-	for n, a := range attrs {
-		if n > 0 {
-			s += ", "
+// HasAttr reports whether attrs contains the flag attribute name, e.g. "Optional"
+func HasAttr(attrs []Attr, name string) bool {
+	for _, a := range attrs {
+		if a.Props == nil && a.Str == name {
+			return true
 		}
-		s += a.String()
 	}
-	return s
+	return false
 }
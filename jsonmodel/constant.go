@@ -8,6 +8,8 @@ type Constant struct {
 	ValueType string
 	Value     ConstantValue
 	Attrs     []Attr
+
+	Path string `json:"-"`
 }
 
 type ConstantValue struct {
@@ -1,5 +1,11 @@
 package jsonmodel
 
+type Param struct {
+	Name  string
+	Type  *Type
+	Attrs []Attr
+}
+
 type Function struct {
 	Ns            *Api
 	Name          string
@@ -11,9 +17,7 @@ type Function struct {
 	Architectures []string
 	Platform      string
 	Attrs         []Attr
-	Params        []struct {
-		Name  string
-		Type  *Type
-		Attrs []Attr
-	}
+	Params        []Param
+
+	Path string `json:"-"`
 }
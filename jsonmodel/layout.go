@@ -0,0 +1,194 @@
+package jsonmodel
+
+import (
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/utils"
+)
+
+// FieldLayout is the position of a struct field in the C layout
+type FieldLayout struct {
+	Offset int
+	Size   int
+
+	//the packing places the field at an offset go does not align its type to,
+	//or the go alignment of the type does not divide the struct size,
+	//so go cannot declare the field with its type
+	Misaligned bool
+}
+
+// packAlign limits the alignment of a member to the packing size of its struct or union
+func (t *Type) packAlign(align int) int {
+	if t.PackingSize != 0 && align > t.PackingSize {
+		return t.PackingSize
+	}
+	return align
+}
+
+// checkMetadataSize cross-checks a computed size against the size recorded in the metadata,
+// which wins if it is larger
+func (t *Type) checkMetadataSize(size int) int {
+	if t.Size == 0 || t.Size == size {
+		return size
+	}
+	diag.Warn(t.Location(), "the type is %d bytes on %s, the metadata says %d",
+		size, utils.CurArch.Name, t.Size)
+	if t.Size > size {
+		return t.Size
+	}
+	return size
+}
+
+// IsSized reports whether the size of the type is known,
+// it is not for types of namespaces that are not loaded
+func (t *Type) IsSized() bool {
+	switch t.Kind {
+	case "Array":
+		return t.Child.IsSized()
+	case "ApiRef":
+		if t.TargetKind == "Com" || config.Cur.IsIntPointer(t.Name) {
+			return true
+		}
+		if refType := t.GetRefType(); refType != nil {
+			return refType.IsSized()
+		}
+		_, ok := config.Cur.ForcedSize(t.Name)
+		return ok
+	case "Struct", "Union":
+		for _, f := range t.Fields {
+			if !f.Type.IsSized() {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// GetFieldLayouts returns the C layout of the fields of a struct, honoring its PackingSize
+func (t *Type) GetFieldLayouts() []FieldLayout {
+	layouts, size, _ := t.layoutStruct()
+	goAligns := make([]int, len(layouts))
+	goAlign := 1
+	for n, f := range t.Fields {
+		goAligns[n] = f.Type.GetGoAlign()
+		if layouts[n].Offset%goAligns[n] != 0 {
+			layouts[n].Misaligned = true
+		} else if goAligns[n] > goAlign {
+			goAlign = goAligns[n]
+		}
+	}
+	//go rounds the struct size up to its alignment
+	for size%goAlign != 0 {
+		for n := range layouts {
+			if goAligns[n] >= goAlign {
+				layouts[n].Misaligned = true
+			}
+		}
+		goAlign /= 2
+	}
+	return layouts
+}
+
+func (t *Type) layoutStruct() ([]FieldLayout, int, int) {
+	var layouts []FieldLayout
+	offset := 0
+	maxAlign := 0
+	for _, f := range t.Fields {
+		size, align := f.Type.GetSize()
+		if align == 0 {
+			align = size
+		}
+		packedAlign := t.packAlign(align)
+		offset = utils.AlignUp(offset, packedAlign)
+		layouts = append(layouts, FieldLayout{
+			Offset: offset,
+			Size:   size,
+		})
+		offset += size
+		if packedAlign > maxAlign {
+			maxAlign = packedAlign
+		}
+	}
+	size := t.checkMetadataSize(utils.AlignUp(offset, maxAlign))
+	return layouts, size, maxAlign
+}
+
+func (t *Type) layoutUnion() (int, int) {
+	maxSize := 0
+	maxAlign := 0
+	for _, f := range t.Fields {
+		size, align := f.Type.GetSize()
+		if align == 0 {
+			if size > 8 {
+				panic(diag.Failure(f.Type.Location(), "the alignment of field %s is unknown", f.Name))
+			}
+			align = size
+		}
+		if size > maxSize {
+			maxSize = size
+		}
+		if align > maxAlign {
+			maxAlign = align
+		}
+	}
+	maxAlign = t.packAlign(maxAlign)
+	return t.checkMetadataSize(utils.AlignUp(maxSize, maxAlign)), maxAlign
+}
+
+// GetGoAlign returns the alignment go gives the generated declaration of the type,
+// which is less than the C one for misaligned fields and for 8 byte values on 32 bit
+func (t *Type) GetGoAlign() int {
+	switch t.Kind {
+	case "Array":
+		return t.Child.GetGoAlign()
+	case "ApiRef":
+		_, overridden := config.Cur.TypeOverride(t.Name)
+		if t.TargetKind != "Com" && !config.Cur.IsIntPointer(t.Name) && !overridden {
+			if refType := t.GetRefType(); refType != nil {
+				return refType.GetGoAlign()
+			}
+		}
+		_, align := t.GetSize()
+		return goAlignOf(align)
+	case "Struct":
+		goAlign := 1
+		for n, l := range t.GetFieldLayouts() {
+			if l.Misaligned {
+				continue
+			}
+			if fAlign := t.Fields[n].Type.GetGoAlign(); fAlign > goAlign {
+				goAlign = fAlign
+			}
+		}
+		return goAlign
+	case "Union":
+		size, align := t.GetSize()
+		for _, f := range t.Fields {
+			if f.Name != "Anonymous" {
+				continue
+			}
+			if fSize, _ := f.Type.GetSize(); fSize == size {
+				return f.Type.GetGoAlign()
+			}
+			break
+		}
+		return goAlignOf(align)
+	default:
+		_, align := t.GetSize()
+		return goAlignOf(align)
+	}
+}
+
+// goAlignOf returns the go alignment of a value with the given C alignment,
+// go aligns 8 byte values to 4 on 32 bit architectures
+func goAlignOf(align int) int {
+	if align > utils.PtrSize {
+		return utils.PtrSize
+	}
+	if align == 0 {
+		return 1
+	}
+	return align
+}
@@ -1,16 +1,24 @@
 package jsonmodel
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/utils"
 	"io/ioutil"
-	"log"
+	"strconv"
 	"strings"
 )
 
 func LoadApis(dir string) []*Api {
 	var apis []*Api
 
-	fis, _ := ioutil.ReadDir(dir)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		diag.Errorf(diag.Location{File: dir}, "%v", err)
+	}
 	for _, fi := range fis {
 		var api Api
 		name := fi.Name()
@@ -18,27 +26,102 @@ func LoadApis(dir string) []*Api {
 		api.Name = name[:pos]
 
 		filePath := dir + "/" + name
+		api.File = filePath
 		sJson, err := ioutil.ReadFile(filePath)
 		if err != nil {
-			log.Fatal(err)
+			diag.Errorf(diag.Location{File: filePath}, "%v", err)
+			continue
 		}
 		err = json.Unmarshal(sJson, &api)
 		if err != nil {
-			log.Fatal(err)
+			diag.Errorf(jsonErrorLocation(filePath, sJson, err),
+				"%v, the namespace is skipped", err)
+			continue
 		}
-		preprocessApi(&api)
 		apis = append(apis, &api)
 	}
+	return PrepareApis(apis)
+}
+
+// PrepareApis prepares the loaded apis for code generation: the declarations for other
+// architectures than utils.CurArch and from disallowed dlls are removed, and the types
+// are registered in TypeRegistry
+func PrepareApis(apis []*Api) []*Api {
+	for _, api := range apis {
+		setApiPaths(api)
+		preprocessApi(api)
+	}
 	TypeRegistry = buildTypeRegistry(apis)
 	return apis
 }
 
+// jsonErrorLocation returns the location of a json decoding error
+func jsonErrorLocation(filePath string, sJson []byte, err error) diag.Location {
+	loc := diag.Location{File: filePath}
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		line := 1 + bytes.Count(sJson[:e.Offset], []byte("\n"))
+		loc.Path = "line " + strconv.Itoa(line)
+	case *json.UnmarshalTypeError:
+		loc.Path = e.Field
+	}
+	return loc
+}
+
+// setApiPaths records where the declarations and type references of api are in its file
+func setApiPaths(api *Api) {
+	for n, c := range api.Constants {
+		c.Path = fmt.Sprintf("Constants[%d]", n)
+		setTypePaths(c.Type, api.File, c.Path+".Type")
+	}
+	for n, t := range api.Types {
+		setTypePaths(t, api.File, fmt.Sprintf("Types[%d]", n))
+	}
+	for n, f := range api.Functions {
+		f.Ns = api
+		setFunctionPaths(f, api.File, fmt.Sprintf("Functions[%d]", n))
+	}
+}
+
+func setFunctionPaths(f *Function, file string, path string) {
+	f.Path = path
+	setTypePaths(f.ReturnType, file, path+".ReturnType")
+	for n, p := range f.Params {
+		setTypePaths(p.Type, file, fmt.Sprintf("%s.Params[%d].Type", path, n))
+	}
+}
+
+func setTypePaths(t *Type, file string, path string) {
+	if t == nil {
+		return
+	}
+	t.File = file
+	t.Path = path
+	setTypePaths(t.Child, file, path+".Child")
+	setTypePaths(t.Def, file, path+".Def")
+	setTypePaths(t.Interface, file, path+".Interface")
+	setTypePaths(t.ReturnType, file, path+".ReturnType")
+	for n, f := range t.Fields {
+		setTypePaths(f.Type, file, fmt.Sprintf("%s.Fields[%d].Type", path, n))
+	}
+	for n, p := range t.Params {
+		setTypePaths(p.Type, file, fmt.Sprintf("%s.Params[%d].Type", path, n))
+	}
+	for n, m := range t.Methods {
+		setFunctionPaths(m, file, fmt.Sprintf("%s.Methods[%d]", path, n))
+	}
+	for n, nt := range t.NestedTypes {
+		setTypePaths(nt, file, fmt.Sprintf("%s.NestedTypes[%d]", path, n))
+	}
+}
+
+// declarations for other architectures than utils.CurArch are ignored
 func ignoreArch(arches []string) bool {
 	if len(arches) == 0 {
 		return false
 	}
 	for _, it := range arches {
-		if it == "X64" {
+		if it == utils.CurArch.Name {
 			return false
 		}
 	}
@@ -88,16 +171,12 @@ func preprocessApi(api *Api) {
 }
 
 func preprocessFunctions(fs []*Function) []*Function {
-	dlls := ",advapi32,comctl32,comdlg32,gdi32,msimg32,gdiplus," +
-		"kernel32,ole32,oleaut32,pdh,shell32,shlwapi,user32,uxtheme," +
-		"version,userenv,imagehlp,"
 	var newFs []*Function
 	for _, f := range fs {
 		if ignoreArch(f.Architectures) {
 			continue
 		}
-		dll := strings.ToLower(f.DllImport)
-		if !strings.Contains(dlls, ","+dll+",") {
+		if !config.Cur.AllowDll(f.DllImport) {
 			continue
 		}
 		newFs = append(newFs, f)
@@ -107,19 +186,25 @@ func preprocessFunctions(fs []*Function) []*Function {
 
 //
 
+// collectTypeMap registers types by fq name and returns them without the duplicates,
+// which are skipped
 func collectTypeMap(path string, parentType *Type,
-	types []*Type, typeMap map[string]*Type) {
+	types []*Type, typeMap map[string]*Type) []*Type {
 
+	var newTypes []*Type
 	for _, t := range types {
 		t.Parent = parentType
 		key := path + "." + t.Name
 		if t0, ok := typeMap[key]; ok {
-			println(t0.Name)
-			panic("?")
+			diag.Errorf(diag.Location{File: t.File, Path: t.Path, Name: key},
+				"duplicate type, %s is used", t0.Path)
+			continue
 		}
 		typeMap[key] = t
-		collectTypeMap(key, t, t.NestedTypes, typeMap)
+		t.NestedTypes = collectTypeMap(key, t, t.NestedTypes, typeMap)
+		newTypes = append(newTypes, t)
 	}
+	return newTypes
 }
 
 //fq name as key
@@ -127,7 +212,7 @@ func buildTypeRegistry(apis []*Api) map[string]*Type {
 	reg := make(map[string]*Type)
 
 	for _, api := range apis {
-		collectTypeMap(api.Name, nil, api.Types, reg)
+		api.Types = collectTypeMap(api.Name, nil, api.Types, reg)
 	}
 	for k, t := range reg {
 		t.FqName = k
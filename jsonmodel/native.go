@@ -1,5 +1,7 @@
 package jsonmodel
 
+import "go-win32api-gen/diag"
+
 func MapNativeGoType(t string) string {
 	switch t {
 	case "SByte":
@@ -37,6 +39,6 @@ func MapNativeGoType(t string) string {
 	case "String":
 		return "string"
 	default:
-		panic("unknown native type " + t)
+		panic(diag.Failure(diag.Location{}, "unknown native type %s", t))
 	}
 }
@@ -1,16 +1,14 @@
 package jsonmodel
 
 import (
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
 	"go-win32api-gen/utils"
-	"log"
 	"math/big"
-	"unsafe"
 )
 
 var TypeRegistry map[string]*Type
 
-const PtrSize = utils.PtrSize
-
 type Type struct {
 	Name          string
 	FqName        string
@@ -26,6 +24,10 @@ type Type struct {
 	Child           *Type
 	NestedTypes     []*Type
 
+	//where the type is declared or referenced in the metadata
+	File string `json:"-"`
+	Path string `json:"-"`
+
 	//
 	ContextType *Type
 	//RefType     *Type
@@ -57,7 +59,7 @@ type Type struct {
 	}
 
 	//Struct
-	//Size int
+	Size        int
 	PackingSize int
 	Fields      []*struct {
 		Name  string
@@ -80,10 +82,15 @@ type Type struct {
 	}
 }
 
+// Location returns where the type is declared or referenced
+func (t *Type) Location() diag.Location {
+	return diag.Location{File: t.File, Path: t.Path, Name: t.FqName}
+}
+
 func (this *Type) String() string {
 	if this.Child != nil {
 		if this.Name != "" {
-			log.Fatal("?")
+			panic(diag.Failure(this.Location(), "type %s has a child type", this.Name))
 		}
 		return "?" + this.Child.Name
 	} else {
@@ -117,8 +124,7 @@ func (this *Type) IsFunctionPointer() bool {
 	if this.Kind == "FunctionPointer" {
 		return true
 	} else if this.Kind == "ApiRef" && this.TargetKind != "Com" {
-		//special
-		if this.Name == "HKL" || this.Name == "HTASK" {
+		if config.Cur.IsNotFunctionPointer(this.Name) {
 			return false
 		}
 		refType := this.GetRefType()
@@ -137,8 +143,7 @@ func (this *Type) IsIntPointer() bool {
 	} else if this.Kind == "NativeTypedef" {
 		return this.Def.IsIntPointer()
 	} else if this.Kind == "ApiRef" {
-		//special
-		if this.Name == "HKL" {
+		if config.Cur.IsIntPointer(this.Name) {
 			return true
 		}
 		refType := this.GetRefType()
@@ -150,6 +155,33 @@ func (this *Type) IsIntPointer() bool {
 	return false
 }
 
+// IsInteger reports whether the type is a native integer type
+func (t *Type) IsInteger() bool {
+	if t.Kind != "Native" {
+		return false
+	}
+	switch t.Name {
+	case "SByte", "Byte", "Int16", "UInt16", "Int32", "UInt32", "Int64", "UInt64", "IntPtr", "UIntPtr":
+		return true
+	}
+	return false
+}
+
+func (this *Type) IsFloat() bool {
+	if this.Kind == "Native" {
+		return this.Name == "Single" || this.Name == "Double"
+	} else if this.Kind == "NativeTypedef" {
+		return this.Def.IsFloat()
+	} else if this.Kind == "ApiRef" && this.TargetKind != "Com" {
+		refType := this.GetRefType()
+		if refType == nil {
+			return false
+		}
+		return refType.IsFloat()
+	}
+	return false
+}
+
 func (this *Type) IsUnsigned() bool {
 	if this.Kind == "Native" {
 		if this.Name == "IntPtr" || this.Name == "UIntPtr" {
@@ -162,8 +194,7 @@ func (this *Type) IsUnsigned() bool {
 	} else if this.Kind == "NativeTypedef" {
 		return this.Def.IsUnsigned()
 	} else if this.Kind == "ApiRef" {
-		//special
-		if this.Name == "HKL" || this.Name == "DEVPROPKEY" {
+		if config.Cur.IsUnsigned(this.Name) {
 			return true
 		}
 		return this.GetRefType().IsUnsigned()
@@ -173,7 +204,7 @@ func (this *Type) IsUnsigned() bool {
 
 func (this *Type) IsStruct() bool {
 	if this.Kind == "ApiRef" && this.TargetKind != "Com" {
-		if this.Name == "HKL" {
+		if config.Cur.IsNotStruct(this.Name) {
 			return false
 		}
 		refType := this.GetRefType()
@@ -214,20 +245,21 @@ func (this *Type) GetRefType() *Type {
 
 func getSizeOfNativeType(name string) int {
 	switch name {
-	case "Char", "Byte", "SByte", "Boolean":
+	//Char is a UTF-16 code unit, declared as uint16
+	case "Byte", "SByte", "Boolean":
 		return 1
-	case "Int16", "UInt16":
+	case "Char", "Int16", "UInt16":
 		return 2
 	case "Int32", "UInt32", "Single":
 		return 4
-	case "Int64", "UInt64", "IntPtr":
+	case "Int64", "UInt64", "Double":
 		return 8
-	case "UIntPtr", "Double":
-		return int(unsafe.Sizeof(uintptr(0)))
+	case "IntPtr", "UIntPtr":
+		return utils.PtrSize
 	case "Guid":
 		return 16
 	default:
-		panic("unknown nantive type " + name)
+		panic(diag.Failure(diag.Location{}, "unknown native type %s", name))
 	}
 }
 
@@ -240,9 +272,9 @@ func (t *Type) GetSize() (int, int) {
 		}
 		return size, size
 	case "PointerTo":
-		return PtrSize, PtrSize
+		return utils.PtrSize, utils.PtrSize
 	case "LPArray":
-		return PtrSize, PtrSize //?
+		return utils.PtrSize, utils.PtrSize //?
 	case "Array":
 		size, alignSize := t.Child.GetSize()
 		count := t.Shape.Size
@@ -251,6 +283,9 @@ func (t *Type) GetSize() (int, int) {
 		}
 		return size * count, alignSize
 	case "ApiRef":
+		if t.TargetKind == "Com" || config.Cur.IsIntPointer(t.Name) {
+			return utils.PtrSize, utils.PtrSize
+		}
 		if t.ContextType != nil {
 			fqRefName := t.ContextType.FqName + "." + t.Name
 			if refType, ok := TypeRegistry[fqRefName]; ok {
@@ -261,16 +296,13 @@ func (t *Type) GetSize() (int, int) {
 		if refType, ok := TypeRegistry[fqRefName]; ok {
 			return refType.GetSize()
 		}
-		//special
-		if t.Name == "POINTER_TOUCH_INFO" {
-			return 144, 8
-		} else if t.Name == "POINTER_PEN_INFO" {
-			return 120, 8
+		if size, ok := config.Cur.ForcedSize(t.Name); ok {
+			return size.Size, size.Align
 		}
-		panic("?")
+		panic(diag.Failure(t.Location(), "the size of %s.%s is unknown", t.Api, t.Name))
 	case "NativeTypedef":
 		if t.Def.Kind == "PointerTo" {
-			return PtrSize, PtrSize
+			return utils.PtrSize, utils.PtrSize
 		}
 		size := getSizeOfNativeType(t.Def.Name)
 		return size, size
@@ -278,37 +310,15 @@ func (t *Type) GetSize() (int, int) {
 		size := getSizeOfNativeType(t.IntegerBase)
 		return size, size
 	case "Com":
-		return PtrSize, PtrSize //?
+		return utils.PtrSize, utils.PtrSize //?
 	case "FunctionPointer":
-		return PtrSize, PtrSize //?
+		return utils.PtrSize, utils.PtrSize //?
 	case "Struct":
-		var fieldSis []utils.SizeInfo
-		for _, f := range t.Fields {
-			size, alignSize := f.Type.GetSize()
-			fieldSis = append(fieldSis, utils.SizeInfo{size, alignSize})
-		}
-		ssi := utils.StructSize(fieldSis...)
-		return ssi.TotalSize, ssi.AlignSize
+		_, size, align := t.layoutStruct()
+		return size, align
 	case "Union":
-		var maxSize int
-		maxAlignSize := 0
-		for _, f := range t.Fields {
-			size, alignSize := f.Type.GetSize()
-			if alignSize == 0 {
-				if size > 8 {
-					panic("?")
-				}
-				alignSize = size
-			}
-			if size > maxSize {
-				maxSize = size
-			}
-			if alignSize > maxAlignSize {
-				maxAlignSize = alignSize
-			}
-		}
-		return maxSize, maxAlignSize
+		return t.layoutUnion()
 	default:
-		panic("?")
+		panic(diag.Failure(t.Location(), "the size of %s types is unknown", t.Kind))
 	}
 }
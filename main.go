@@ -2,13 +2,19 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"go-win32api-gen/codegen"
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
 	"go-win32api-gen/gomodel"
 	"go-win32api-gen/jsonmodel"
 	"go-win32api-gen/utils"
-	"io/ioutil"
+	"go-win32api-gen/winmd"
+	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -24,15 +30,14 @@ func MapGoTypeInfo(t *jsonmodel.Type) gomodel.TypeInfo {
 func _mapGoTypeInfo(t *jsonmodel.Type) gomodel.TypeInfo {
 	switch t.Kind {
 	case "ApiRef":
-		//special
-		if t.Name == "LARGE_INTEGER" {
-			return gomodel.NewTypeInfo("int64")
-		}
-		if t.Name == "ULARGE_INTEGER" {
-			return gomodel.NewTypeInfo("uint64")
+		if goType, ok := config.Cur.TypeOverride(t.Name); ok {
+			if goType[0] == '*' || goType == "unsafe.Pointer" {
+				return gomodel.NewPointerTypeInfo(goType)
+			}
+			return gomodel.NewTypeInfo(goType)
 		}
 		if t.TargetKind == "Com" {
-			return gomodel.NewPointerTypeInfo("*" + t.Name)
+			return gomodel.NewPointerTypeInfo("*" + qualifyName(t.Api, goName(t.Name)))
 		}
 		if t.ContextType != nil {
 			fqRefName := t.ContextType.FqName + "." + t.Name
@@ -52,7 +57,7 @@ func _mapGoTypeInfo(t *jsonmodel.Type) gomodel.TypeInfo {
 				//?panic("?")
 			}
 		}
-		name := utils.CapName(t.Name)
+		name := qualifyName(t.Api, goName(t.Name))
 		gti := gomodel.NewTypeInfo(name)
 		if t.IsPointer() {
 			gti.Kind = gomodel.TypeKindPointer
@@ -62,16 +67,27 @@ func _mapGoTypeInfo(t *jsonmodel.Type) gomodel.TypeInfo {
 			gti.Kind = gomodel.TypeKindFunc
 		} else if t.IsStruct() {
 			gti.Kind = gomodel.TypeKindStruct
+		} else if t.IsFloat() {
+			gti.Kind = gomodel.TypeKindFloat
+		}
+		if t.GetRefType() != nil && t.TargetKind != "Com" {
 			tSize, aSize := t.GetSize()
-			gti.Size = utils.SizeInfo{tSize, aSize}
+			gti.Size = utils.SizeInfo{TotalSize: tSize, AlignSize: aSize}
 		}
+		gti.Defined = isDefinedType(t.GetRefType())
 		return gti
 	case "Native":
 		goType := jsonmodel.MapNativeGoType(t.Name)
 		gti := gomodel.NewTypeInfo(goType)
 		if goType == "syscall.GUID" {
 			gti.Kind = gomodel.TypeKindStruct
-			gti.Size = utils.SizeInfo{16, 4}
+			gti.Size = utils.SizeInfo{TotalSize: 16, AlignSize: 4}
+		} else if goType != "" && goType != "string" {
+			if t.IsFloat() {
+				gti.Kind = gomodel.TypeKindFloat
+			}
+			tSize, aSize := t.GetSize()
+			gti.Size = utils.SizeInfo{TotalSize: tSize, AlignSize: aSize}
 		}
 		return gti
 	case "PointerTo", "LPArray":
@@ -96,10 +112,10 @@ func _mapGoTypeInfo(t *jsonmodel.Type) gomodel.TypeInfo {
 		gti := gomodel.NewTypeInfo(t.Name)
 		gti.Kind = gomodel.TypeKindStruct
 		tSize, aSize := t.GetSize()
-		gti.Size = utils.SizeInfo{tSize, aSize}
+		gti.Size = utils.SizeInfo{TotalSize: tSize, AlignSize: aSize}
 		return gti
 	default:
-		panic("unknown kind " + t.Kind)
+		panic(diag.Failure(t.Location(), "unknown type kind %s", t.Kind))
 	}
 }
 
@@ -107,10 +123,6 @@ func buildConstValue(c *jsonmodel.Constant) string {
 	if c.Type.Name == "Guid" {
 		return utils.BuildGuidExpr(c.Value.Str)
 	}
-	//?
-	if c.Type.Name == "DEVPROPKEY" {
-		return ""
-	}
 	sValue := c.Value.String()
 
 	if c.Type.IsUnsigned() {
@@ -131,121 +143,329 @@ func buildConstValue(c *jsonmodel.Constant) string {
 	return sValue
 }
 
-func buildGoApi(api *jsonmodel.Api) *gomodel.GoApi {
-	goApi := &gomodel.GoApi{}
-	goApi.Name = api.Name
+// generate go error returns, see gomodel.GoApi.ErrorValues
+var gErrorValues bool
 
-	typeNameSet := make(map[string]bool)
+// generate function wrappers, see gomodel.GoApi.Wrappers
+var gWrappers bool
 
-	for _, it := range api.Constants {
-		//special..
-		if it.Type.Name == "PROPERTYKEY" {
+// declare native typedefs as defined types rather than aliases
+var gDefinedTypes bool
+
+// isDefinedType reports whether t is declared as a defined type,
+// values of defined types are converted to pass them as uintptr
+func isDefinedType(t *jsonmodel.Type) bool {
+	if t == nil || t.Kind != "NativeTypedef" {
+		return false
+	}
+	return gDefinedTypes || isHandle(t)
+}
+
+func buildParamAttrs(attrs []jsonmodel.Attr) gomodel.ParamAttrs {
+	var pa gomodel.ParamAttrs
+	for _, a := range attrs {
+		if a.Props != nil {
+			if a.Props["Kind"] == "MemorySize" {
+				pa.Sized = true
+			}
 			continue
 		}
-		sValue := buildConstValue(it)
+		switch a.Str {
+		case "In":
+			pa.In = true
+		case "Out":
+			pa.Out = true
+		case "Const":
+			pa.Const = true
+		case "Optional":
+			pa.Optional = true
+		case "Reserved":
+			pa.Reserved = true
+		}
+	}
+	return pa
+}
 
-		cti := MapGoTypeInfo(it.Type)
-		c := gomodel.Const{
-			Name:  utils.CapName(it.Name),
-			Value: sValue,
-			Type:  cti.Name,
+// sizeParamIndex returns the index of the parameter holding the size of the buffer p, or -1
+func sizeParamIndex(p jsonmodel.Param) int {
+	if p.Type.Kind == "LPArray" && p.Type.CountConst <= 0 {
+		return p.Type.CountParamIndex
+	}
+	for _, a := range p.Attrs {
+		index, ok := a.Props["BytesParamIndex"].(float64)
+		if ok && a.Props["Kind"] == "MemorySize" {
+			return int(index)
 		}
-		if it.Type.IsPointer() || cti.IsStruct() {
-			goApi.VarConsts = append(goApi.VarConsts, c)
-		} else {
-			goApi.Consts = append(goApi.Consts, c)
+	}
+	return -1
+}
+
+// isSizeType reports whether a parameter can hold the size of a buffer,
+// an integer or a pointer to one
+func isSizeType(t *jsonmodel.Type) bool {
+	if t.Kind == "PointerTo" {
+		t = t.Child
+	}
+	return t.IsInteger()
+}
+
+// buildBuffers sets the buffers of the parameters of a function and marks the parameters
+// holding their sizes. Buffers sized in bytes are only taken as a slice if they are of bytes,
+// buffers sharing a size parameter are not taken as slices, their lengths could differ.
+func buildBuffers(params []jsonmodel.Param, gps []gomodel.Param) {
+	sizeUsers := make(map[int]int)
+	for _, p := range params {
+		index := sizeParamIndex(p)
+		if index >= 0 && index < len(gps) {
+			gps[index].Attrs.BufferSize = true
+			sizeUsers[index]++
 		}
 	}
+	for n, p := range params {
+		gp := &gps[n]
+		if !gp.Type.IsPointer() || gp.Attrs.Reserved {
+			continue
+		}
+		elemType := strings.TrimPrefix(gp.Type.Name, "*")
+		if gp.Type.Name == "unsafe.Pointer" {
+			elemType = "byte"
+		}
+		index := sizeParamIndex(p)
+		switch {
+		case p.Type.Kind == "LPArray" && p.Type.CountConst > 0:
+			gp.Buffer = &gomodel.Buffer{ElemType: elemType, SizeParam: -1, Count: p.Type.CountConst}
+		case index < 0 || index >= len(gps) || index == n || sizeUsers[index] > 1 ||
+			!isSizeType(params[index].Type) || gps[index].Attrs.Reserved:
+		case p.Type.Kind == "LPArray" && elemType != gp.Type.Name:
+			gp.Buffer = &gomodel.Buffer{ElemType: elemType, SizeParam: index}
+		case gp.Attrs.Sized && (elemType == "byte" || elemType == "uint8"):
+			gp.Buffer = &gomodel.Buffer{ElemType: elemType, SizeParam: index}
+		}
+	}
+}
 
-	structNameMap := make(map[string]bool)
-	for _, t := range api.Types {
-		goTypeName := utils.CapName(t.Name)
-		switch t.Kind {
-		case "NativeTypedef":
-			typeAlias := gomodel.Alias{
-				Name:     goTypeName,
-				RealName: MapGoTypeInfo(t.Def).Name,
+// inStringKind returns the kind of string a parameter is if the function only reads it
+func inStringKind(t *jsonmodel.Type, attrs []jsonmodel.Attr) gomodel.StringKind {
+	if t.Kind != "ApiRef" || t.Api != "Foundation" ||
+		!jsonmodel.HasAttr(attrs, "In") || jsonmodel.HasAttr(attrs, "Out") ||
+		jsonmodel.HasAttr(attrs, "NotNullTerminated") || jsonmodel.HasAttr(attrs, "NullNullTerminated") {
+		return gomodel.NotString
+	}
+	switch t.Name {
+	case "PWSTR":
+		return gomodel.UTF16String
+	case "PSTR":
+		return gomodel.ANSIString
+	}
+	return gomodel.NotString
+}
+
+// gCoCreateInstance is the System.Com function used by the generated creation helpers,
+// nil when System.Com is not generated
+var gCoCreateInstance *jsonmodel.Function
+
+// findCoCreateInstance returns CoCreateInstance if it is generated, or nil
+func findCoCreateInstance(apis []*jsonmodel.Api, filter *nsFilter) *jsonmodel.Function {
+	for _, api := range apis {
+		if api.Name != "System.Com" || !filter.Match(api.Name) {
+			continue
+		}
+		for _, f := range api.Functions {
+			if f.Name == "CoCreateInstance" && !config.Cur.SkipFunction(f.Name) &&
+				inClosure(api.Name, f.Name) {
+				return f
 			}
-			goApi.TypeAliases = append(goApi.TypeAliases, typeAlias)
-		case "Enum":
-			enum := gomodel.Enum{
-				Name:     goTypeName,
-				Flags:    t.Flags,
-				BaseType: jsonmodel.MapNativeGoType(t.IntegerBase),
+		}
+	}
+	return nil
+}
+
+// classInterface returns the interface the creation helper of the COM class t returns:
+// the interface of its namespace named after it, e.g. IShellLinkW of ShellLink, or IUnknown
+func classInterface(t *jsonmodel.Type) *jsonmodel.Type {
+	ns := strings.TrimSuffix(t.FqName, "."+t.Name)
+	for _, name := range []string{"I" + t.Name, "I" + t.Name + "W"} {
+		if it := jsonmodel.TypeRegistry[ns+"."+name]; it != nil && it.Kind == "Com" && it.Guid != "" {
+			return it
+		}
+	}
+	return jsonmodel.TypeRegistry["System.Com.IUnknown"]
+}
+
+func buildComClass(t *jsonmodel.Type, goTypeName string) gomodel.ComClass {
+	cc := gomodel.ComClass{
+		Name:  goTypeName,
+		CLSID: t.Guid,
+	}
+	if it := classInterface(t); it != nil {
+		ns := strings.TrimSuffix(it.FqName, "."+it.Name)
+		if inClosure(ns, it.Name) {
+			cc.Interface = qualifyName(ns, goName(it.Name))
+			cc.InterfaceIID = qualifyName(ns, "IID_"+goName(it.Name))
+		}
+	}
+	return cc
+}
+
+func buildCoCreateInstance(f *jsonmodel.Function) *gomodel.Func {
+	gf := &gomodel.Func{
+		Name:       qualifyName("System.Com", goName(f.Name)),
+		ProcName:   f.Name,
+		ReturnType: MapGoTypeInfo(f.ReturnType),
+	}
+	for _, p := range f.Params {
+		gf.Params = append(gf.Params, gomodel.Param{
+			Name: utils.SafeGoName(p.Name),
+			Type: MapGoTypeInfo(p.Type),
+		})
+	}
+	return gf
+}
+
+func buildGoApi(api *jsonmodel.Api) *gomodel.GoApi {
+	goApi := &gomodel.GoApi{}
+	goApi.Name = api.Name
+	goApi.ErrorValues = gErrorValues
+	goApi.Wrappers = gWrappers
+	goApi.LeakCheck = gLeakCheck
+
+	for _, it := range api.Constants {
+		if config.Cur.SkipConstant(it.Name) ||
+			config.Cur.SkipConstantType(it.Type.Name) || !inClosure(api.Name, it.Name) {
+			continue
+		}
+		diag.Guard(declLocation(api, it.Path, it.Name), func() {
+			sValue := buildConstValue(it)
+
+			cti := MapGoTypeInfo(it.Type)
+			c := gomodel.Const{
+				Name:  goName(it.Name),
+				Value: sValue,
+				Type:  cti.Name,
 			}
-			for _, v := range t.Values {
-				value := gomodel.EnumValue{
-					Name:  utils.CapName(v.Name),
-					Value: v.Value.String(),
-				}
-				enum.Values = append(enum.Values, value)
+			if it.Type.IsPointer() || cti.IsStruct() {
+				goApi.VarConsts = append(goApi.VarConsts, c)
+			} else {
+				goApi.Consts = append(goApi.Consts, c)
 			}
-			goApi.Enums = append(goApi.Enums, enum)
-		case "Struct":
-			ss := buildGoStruct(t, "", typeNameSet)
-			goApi.Structs = append(goApi.Structs, ss...)
-			for _, s := range ss {
-				structNameMap[s.Name] = true
+		})
+	}
+
+	structNameMap := make(map[string]bool)
+	for _, t := range api.Types {
+		if config.Cur.SkipType(t.Name) || !inClosure(api.Name, t.Name) {
+			continue
+		}
+		diag.Guard(declLocation(api, t.Path, t.Name), func() {
+			goTypeName := goName(t.Name)
+			switch t.Kind {
+			case "NativeTypedef":
+				if isHandle(t) {
+					goApi.Handles = append(goApi.Handles, buildHandle(api, t, goApi))
+					break
+				}
+				typeAlias := gomodel.Alias{
+					Name:     goTypeName,
+					RealName: MapGoTypeInfo(t.Def).Name,
+					Defined:  gDefinedTypes,
+				}
+				goApi.TypeAliases = append(goApi.TypeAliases, typeAlias)
+			case "Enum":
+				enum := gomodel.Enum{
+					Name:     goTypeName,
+					Flags:    t.Flags,
+					BaseType: jsonmodel.MapNativeGoType(t.IntegerBase),
+				}
+				for _, v := range t.Values {
+					value := gomodel.EnumValue{
+						Name:  goName(v.Name),
+						Value: v.Value.String(),
+					}
+					enum.Values = append(enum.Values, value)
+				}
+				goApi.Enums = append(goApi.Enums, enum)
+			case "Struct":
+				ss := buildGoStruct(t, "")
+				goApi.Structs = append(goApi.Structs, ss...)
+				for _, s := range ss {
+					structNameMap[s.Name] = true
+				}
+			case "Union":
+				ss := buildUnionStructs(t, "")
+				goApi.Structs = append(goApi.Structs, ss...)
+			case "Com":
+				c := buildCom(t)
+				goApi.Coms = append(goApi.Coms, c)
+			case "ComClassID":
+				goApi.ComClasses = append(goApi.ComClasses, buildComClass(t, goTypeName))
+			case "FunctionPointer":
+				gf := gomodel.Func{
+					Name: goTypeName,
+				}
+				for _, p := range t.Params {
+					gp := gomodel.Param{
+						Name: utils.SafeGoName(p.Name),
+						Type: MapGoTypeInfo(p.Type),
+					}
+					gf.Params = append(gf.Params, gp)
+				}
+				if t.ReturnType != nil {
+					gf.ReturnType = MapGoTypeInfo(t.ReturnType)
+				}
+				goApi.FuncTypes = append(goApi.FuncTypes, gf)
+			default:
+				diag.Errorf(declLocation(api, t.Path, t.Name), "unknown type kind %s, the type is skipped",
+					t.Kind)
 			}
-		case "Union":
-			ss := buildUnionStructs(t, "", typeNameSet)
-			goApi.Structs = append(goApi.Structs, ss...)
-		case "Com":
-			c := buildCom(t, typeNameSet)
-			goApi.Coms = append(goApi.Coms, c)
-		case "ComClassID":
-			//
-		case "FunctionPointer":
+		})
+	}
+
+	funcNameMap := make(map[string]bool)
+	for _, it := range api.Functions {
+		if config.Cur.SkipFunction(it.Name) || !inClosure(api.Name, it.Name) {
+			continue
+		}
+		diag.Guard(declLocation(api, it.Path, it.Name), func() {
 			gf := gomodel.Func{
-				Name: t.Name,
+				Name:       goName(it.Name),
+				ProcName:   it.Name,
+				GrowBuffer: config.Cur.GrowBuffer(it.Name),
 			}
-			for _, p := range t.Params {
+			for _, p := range it.Params {
+				ti := MapGoTypeInfo(p.Type)
 				gp := gomodel.Param{
-					Name: utils.SafeGoName(p.Name),
-					Type: MapGoTypeInfo(p.Type),
+					Name:     utils.SafeGoName(p.Name),
+					Type:     ti,
+					Attrs:    buildParamAttrs(p.Attrs),
+					InString: inStringKind(p.Type, p.Attrs),
 				}
 				gf.Params = append(gf.Params, gp)
 			}
-			if t.ReturnType != nil {
-				gf.ReturnType = MapGoTypeInfo(t.ReturnType)
+			buildBuffers(it.Params, gf.Params)
+			if it.ReturnType != nil {
+				ti := MapGoTypeInfo(it.ReturnType)
+				gf.ReturnType = ti
 			}
-			goApi.FuncTypes = append(goApi.FuncTypes, gf)
-		default:
-			panic("?")
-		}
-	}
-
-	funcNameMap := make(map[string]bool)
-	for _, it := range api.Functions {
-		gf := gomodel.Func{
-			Name: it.Name,
-		}
-		for _, p := range it.Params {
-			ti := MapGoTypeInfo(p.Type)
-			gp := gomodel.Param{
-				Name:  utils.SafeGoName(p.Name),
-				Type:  ti,
-				Attrs: jsonmodel.BuildAttrsStr(p.Attrs),
+			if it.SetLastError {
+				gf.ReturnError = true
+				goApi.ErrorType = qualifyName("Foundation", "WIN32_ERROR")
 			}
-			typeNameSet[ti.Name] = true
-			gf.Params = append(gf.Params, gp)
-		}
-		if it.ReturnType != nil {
-			ti := MapGoTypeInfo(it.ReturnType)
-			gf.ReturnType = ti
-			typeNameSet[ti.Name] = true
-		}
-		if it.SetLastError {
-			gf.ReturnError = true
-		}
-		gf.Dll = it.DllImport
-		funcNameMap[gf.Name] = true
-		goApi.Funcs = append(goApi.Funcs, gf)
+			gf.Dll = it.DllImport
+			funcNameMap[gf.Name] = true
+			goApi.Funcs = append(goApi.Funcs, gf)
+		})
+	}
+	if gCoCreateInstance != nil && len(goApi.ComClasses) > 0 {
+		//without CoCreateInstance the creation helpers are skipped
+		f := gCoCreateInstance
+		diag.Guard(declLocation(f.Ns, f.Path, f.Name), func() {
+			goApi.CoCreateInstance = buildCoCreateInstance(gCoCreateInstance)
+		})
 	}
 
 	for _, a := range api.UnicodeAliases {
-		a = utils.CapName(a)
-		uName := a + "W"
+		uName := goName(a + "W")
+		a = goName(a)
 		if _, ok := structNameMap[uName]; ok {
 			goApi.StructAliases = append(goApi.StructAliases, gomodel.Alias{
 				Name:     a,
@@ -259,42 +479,35 @@ func buildGoApi(api *jsonmodel.Api) *gomodel.GoApi {
 		}
 	}
 
-	//
-	hasSyscall := false
-	hasUnsafe := false
-	if len(goApi.Funcs) > 0 || len(goApi.Coms) > 0 {
-		hasSyscall = true
-	}
-	delete(typeNameSet, "")
-	for k, _ := range typeNameSet {
-		if strings.Contains(k, "unsafe.") || k[0] == '*' {
-			hasUnsafe = true
-		} else if strings.Contains(k, "syscall.") {
-			hasSyscall = true
-		}
-	}
-	for _, s := range goApi.Structs {
-		if len(s.UnionFields) > 0 {
-			hasUnsafe = true
-		}
-	}
-	if hasUnsafe {
-		goApi.Imports = append(goApi.Imports, "unsafe")
-	}
-	if hasSyscall {
-		goApi.Imports = append(goApi.Imports, "syscall")
-	}
-
 	return goApi
 }
 
-func buildCom(t *jsonmodel.Type, set map[string]bool) gomodel.Com {
+// declLocation returns the location of a declaration of api
+func declLocation(api *jsonmodel.Api, path string, name string) diag.Location {
+	return diag.Location{File: api.File, Path: path, Name: api.Name + "." + name}
+}
+
+func buildCom(t *jsonmodel.Type) gomodel.Com {
 	com := gomodel.Com{
-		Name: t.Name,
+		Name: goName(t.Name),
 	}
 	com.IID = t.Guid
 	if t.Interface != nil {
-		com.Super = t.Interface.Name
+		com.Super = qualifyName(t.Interface.Api, goName(t.Interface.Name))
+	}
+	for ref := t.Interface; ref != nil; {
+		refName := qualifyName(ref.Api, goName(ref.Name))
+		superType := ref.GetRefType()
+		if superType == nil {
+			break
+		}
+		if superType.Guid != "" {
+			com.SuperIIDs = append(com.SuperIIDs, qualifyName(ref.Api, "IID_"+goName(ref.Name)))
+		}
+		if superType.Interface == nil {
+			com.Unknown = refName
+		}
+		ref = superType.Interface
 	}
 	for _, method := range t.Methods {
 		gm := gomodel.Func{
@@ -302,8 +515,9 @@ func buildCom(t *jsonmodel.Type, set map[string]bool) gomodel.Com {
 		}
 		for _, p := range method.Params {
 			gp := gomodel.Param{
-				Name: utils.SafeGoName(p.Name),
-				Type: MapGoTypeInfo(p.Type),
+				Name:  utils.SafeGoName(p.Name),
+				Type:  MapGoTypeInfo(p.Type),
+				Attrs: buildParamAttrs(p.Attrs),
 			}
 			gm.Params = append(gm.Params, gp)
 		}
@@ -315,14 +529,13 @@ func buildCom(t *jsonmodel.Type, set map[string]bool) gomodel.Com {
 	return com
 }
 
-func buildUnionStructs(t *jsonmodel.Type, parentGoTypeName string,
-	typeNameSet map[string]bool) []gomodel.Struct {
+func buildUnionStructs(t *jsonmodel.Type, parentGoTypeName string) []gomodel.Struct {
 
 	goTypeName := getGoTypeName(parentGoTypeName, t)
 
 	var ss []gomodel.Struct
 
-	ss = buildNestedTypes(goTypeName, t, typeNameSet)
+	ss = buildNestedTypes(goTypeName, t)
 
 	s := gomodel.Struct{
 		Name: goTypeName,
@@ -331,7 +544,7 @@ func buildUnionStructs(t *jsonmodel.Type, parentGoTypeName string,
 	size, alignSize := t.GetSize()
 	if alignSize == 0 {
 		if size > 8 {
-			panic("?")
+			panic(diag.Failure(t.Location(), "the alignment of the union is unknown"))
 		}
 		alignSize = size
 	}
@@ -366,7 +579,7 @@ func buildUnionStructs(t *jsonmodel.Type, parentGoTypeName string,
 		case 8:
 			elemType = "uint64"
 		default:
-			panic("?")
+			panic(diag.Failure(t.Location(), "unsupported union alignment %d", alignSize))
 		}
 		elemCount := size / alignSize
 		goType := fmt.Sprintf("[%d]%s", elemCount, elemType)
@@ -386,36 +599,41 @@ func buildUnionStructs(t *jsonmodel.Type, parentGoTypeName string,
 			Type: MapGoTypeInfo(f.Type),
 		})
 	}
+	s.Size = size
+	s.Align = t.GetGoAlign()
 
 	ss = append(ss, s)
 	return ss
 }
 
 func getGoTypeName(parentGoTypeName string, t *jsonmodel.Type) string {
-	goTypeName := utils.CapName(t.Name)
-	if parentGoTypeName != "" {
-		goTypeName = parentGoTypeName + "_" + goTypeName
+	if parentGoTypeName == "" {
+		return goName(t.Name)
 	}
-	return goTypeName
+	return parentGoTypeName + "_" + utils.CapName(t.Name)
+}
+
+// goName returns the go name of a top level metadata symbol
+func goName(name string) string {
+	return config.Cur.GoName(name, utils.CapName(name))
 }
 
 func buildNestedTypes(parentGoTypeName string,
-	parentType *jsonmodel.Type, typeNameSet map[string]bool) []gomodel.Struct {
+	parentType *jsonmodel.Type) []gomodel.Struct {
 	var ss []gomodel.Struct
 	for _, nestedType := range parentType.NestedTypes {
 		if nestedType.Kind == "Struct" {
-			nestedSs := buildGoStruct(nestedType, parentGoTypeName, typeNameSet)
+			nestedSs := buildGoStruct(nestedType, parentGoTypeName)
 			ss = append(ss, nestedSs...)
 		} else if nestedType.Kind == "Union" {
-			nestedSs := buildUnionStructs(nestedType, parentGoTypeName, typeNameSet)
+			nestedSs := buildUnionStructs(nestedType, parentGoTypeName)
 			ss = append(ss, nestedSs...)
 		}
 	}
 	return ss
 }
 
-func buildGoStruct(t *jsonmodel.Type, parentGoTypeName string,
-	typeNameSet map[string]bool) []gomodel.Struct {
+func buildGoStruct(t *jsonmodel.Type, parentGoTypeName string) []gomodel.Struct {
 
 	goTypeName := getGoTypeName(parentGoTypeName, t)
 
@@ -424,42 +642,381 @@ func buildGoStruct(t *jsonmodel.Type, parentGoTypeName string,
 	}
 
 	var ss []gomodel.Struct
-	ss = buildNestedTypes(goTypeName, t, typeNameSet)
-	for _, it := range t.Fields {
-		ti := MapGoTypeInfo(it.Type)
+	ss = buildNestedTypes(goTypeName, t)
+
+	if !t.IsSized() {
+		diag.Warn(t.Location(), "the layout is unknown, the struct is not padded")
+		for _, it := range t.Fields {
+			s.Fields = append(s.Fields, gomodel.StructField{
+				Name: utils.CapName(it.Name),
+				Type: MapGoTypeInfo(it.Type),
+			})
+		}
+		return append(ss, s)
+	}
+
+	//go aligns fields naturally, fields the packing misaligns become byte arrays
+	//and explicit padding keeps the others at their C offsets
+	goOffset := 0
+	for n, l := range t.GetFieldLayouts() {
+		it := t.Fields[n]
+		var ti gomodel.TypeInfo
+		goAlign := 1
+		if l.Misaligned {
+			ti = gomodel.NewTypeInfo(fmt.Sprintf("[%d]byte", l.Size))
+		} else {
+			ti = MapGoTypeInfo(it.Type)
+			goAlign = it.Type.GetGoAlign()
+		}
+		goOffset = utils.AlignUp(goOffset, goAlign)
+		if goOffset > l.Offset {
+			panic(diag.Failure(it.Type.Location(), "field %s is at %d in go and at %d in C",
+				it.Name, goOffset, l.Offset))
+		}
+		s.Fields = appendPadding(s.Fields, l.Offset-goOffset)
 		f := gomodel.StructField{
-			Name: utils.CapName(it.Name),
-			Type: ti,
+			Name:   utils.CapName(it.Name),
+			Type:   ti,
+			Offset: l.Offset,
 		}
-		typeNameSet[ti.Name] = true
 		s.Fields = append(s.Fields, f)
+		goOffset = l.Offset + l.Size
+	}
+	s.Size, _ = t.GetSize()
+	s.Align = t.GetGoAlign()
+	if utils.AlignUp(goOffset, s.Align) < s.Size {
+		s.Fields = appendPadding(s.Fields, s.Size-goOffset)
 	}
 	ss = append(ss, s)
 	return ss
 }
 
+func appendPadding(fields []gomodel.StructField, size int) []gomodel.StructField {
+	if size == 0 {
+		return fields
+	}
+	return append(fields, gomodel.StructField{
+		Name: "_",
+		Type: gomodel.NewTypeInfo(fmt.Sprintf("[%d]byte", size)),
+	})
+}
+
 /*
-  manual edit:
-		VARIANT.cVal -> int8
-		VARIANT.pcVal -> *int8
+	  manual edit:
+			VARIANT.cVal -> int8
+			VARIANT.pcVal -> *int8
 */
 func main() {
+	args := os.Args[1:]
+	cmd := "gen"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "gen":
+		runGen(args)
+	case "prune":
+		runPrune(args)
+	case "diff":
+		runDiff(args)
+	case "apicheck":
+		runApiCheck(args)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown command: "+cmd)
+		fmt.Fprintln(os.Stderr, "usage: go-win32api-gen [gen|prune|diff|apicheck] [flags]")
+		os.Exit(2)
+	}
+}
+
+// genFlags are the flags of the gen command, prune takes them as well
+type genFlags struct {
+	inDir        *string
+	winmdFile    *string
+	outDir       *string
+	pkgName      *string
+	include      *string
+	exclude      *string
+	archList     *string
+	errorValues  *bool
+	split        *bool
+	module       *string
+	configFile   *string
+	rootList     *string
+	layoutTests  *bool
+	wrappers     *bool
+	definedTypes *bool
+	handles      *bool
+	leakCheck    *bool
+}
 
-	gTypeInfoMap = make(map[string]*jsonmodel.Type)
+func newGenFlags(fs *flag.FlagSet) *genFlags {
+	return &genFlags{
+		inDir: fs.String("in", "win32json/api", "win32json api directory"),
+		winmdFile: fs.String("winmd", "",
+			"Windows.Win32.winmd file to read the metadata from instead of the -in directory"),
+		outDir:  fs.String("out", "win32", "output directory"),
+		pkgName: fs.String("pkg", "win32", "go package name of the generated code"),
+		include: fs.String("include", "",
+			"comma separated namespace globs to generate, e.g. \"UI.*,System.Com\""),
+		exclude: fs.String("exclude", "",
+			"comma separated namespace globs to skip, e.g. \"UI.Shell\""),
+		archList: fs.String("arch", "X64",
+			"comma separated target architectures: X64, X86, Arm64"),
+		errorValues: fs.Bool("errors", false,
+			"return go errors from SetLastError functions and HRESULT returning methods"),
+		split: fs.Bool("split", false,
+			"generate a package per namespace instead of a single package"),
+		module: fs.String("module", "",
+			"import path of the output directory, required with -split"),
+		configFile: fs.String("config", "",
+			"yaml or json generator configuration applied on top of the built-in one"),
+		rootList: fs.String("roots", "",
+			"comma separated functions, interfaces, structs and constants to generate with their dependencies, or @file"),
+		layoutTests: fs.Bool("layout-tests", false,
+			"generate tests asserting the size, alignment and field offsets of the structs"),
+		wrappers: fs.Bool("wrappers", false,
+			"generate XxxGo wrappers of the functions taking go strings and returning out parameters"),
+		definedTypes: fs.Bool("defined-types", false,
+			"declare native typedefs such as HWND as defined types rather than aliases of uintptr"),
+		handles: fs.Bool("handles", false,
+			"declare handle types as defined types with Close and conversion methods"),
+		leakCheck: fs.Bool("leak-check", false,
+			"generate Owned handles reporting those garbage collected without Close, with -handles"),
+	}
+}
 
-	apis := jsonmodel.LoadApis("win32json/api")
+// loadApis loads the metadata from the -winmd file or the -in directory
+func (this *genFlags) loadApis() ([]*jsonmodel.Api, error) {
+	if *this.winmdFile != "" {
+		//an error fails the run rather than each arch
+		return winmd.LoadApis(*this.winmdFile)
+	}
+	return jsonmodel.LoadApis(*this.inDir), nil
+}
 
-	os.Mkdir("win32", os.ModePerm)
-	for _, api := range apis {
-		goApi := buildGoApi(api)
+func runGen(args []string) {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	opts := newGenFlags(fs)
+	fs.Parse(args)
+	if err := generate(opts); err != nil {
+		log.Fatal(err)
+	}
+}
 
-		w := bytes.NewBuffer(nil)
-		codegen.Gen(goApi, w)
-		s := w.String()
+// errSkipped is returned by generate when declarations in error were skipped
+var errSkipped = errors.New("declarations in error were skipped, the output is incomplete")
+
+// generate generates the bindings as configured by the flags
+func generate(opts *genFlags) error {
+	//a run does not see the diagnostics and configuration of the previous one
+	diag.Reset()
+	config.Cur = config.Default()
+	if *opts.configFile != "" {
+		c, err := config.Load(*opts.configFile)
+		if err != nil {
+			return err
+		}
+		config.Cur = c
+	}
+
+	filter, err := newNsFilter(*opts.include, *opts.exclude)
+	if err != nil {
+		return err
+	}
 
-		filePath := "win32/" + goApi.Name + ".go"
-		ioutil.WriteFile(filePath, []byte(s), os.ModePerm)
+	arches, err := utils.ParseArches(*opts.archList)
+	if err != nil {
+		return err
 	}
 
+	var roots []string
+	gMatchedRoots = make(map[string]bool)
+	if *opts.rootList != "" {
+		roots, err = parseRoots(*opts.rootList)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *opts.split && *opts.module == "" {
+		return errors.New("-module is required with -split")
+	}
+	//references into the namespaces filtered out are reported in both modes
+	deps := make(map[string]map[string]bool)
+	for _, arch := range arches {
+		utils.SetArch(arch)
+		apis, err := opts.loadApis()
+		if err != nil {
+			return err
+		}
+		collectNsDeps(apis, filter, deps)
+	}
+	reportMissingNamespaces(deps, *opts.split)
+	gPackages = nil
+	if *opts.split {
+		gPackages = planPackages(deps, *opts.module)
+	}
+
+	//namespace name -> go api per arch
+	goApiMap := make(map[string][]*gomodel.GoApi)
+	var nsNames []string
+	for n, arch := range arches {
+		utils.SetArch(arch)
+		gTypeInfoMap = make(map[string]*jsonmodel.Type)
+
+		//all apis are loaded so that cross namespace refs can be resolved
+		apis, err := opts.loadApis()
+		if err != nil {
+			return err
+		}
+		gClosure = nil
+		if roots != nil {
+			gClosure = buildClosure(apis, roots, *opts.errorValues)
+		}
+		gCoCreateInstance = findCoCreateInstance(apis, filter)
+		collectHandleInfo(apis, filter)
+		for _, api := range apis {
+			if !filter.Match(api.Name) {
+				continue
+			}
+			gCurPackage = gPackages[api.Name]
+			gErrorValues = *opts.errorValues
+			gWrappers = *opts.wrappers
+			gDefinedTypes = *opts.definedTypes
+			gHandles = *opts.handles
+			gLeakCheck = *opts.handles && *opts.leakCheck
+			goApi := buildGoApi(api)
+			if gClosure != nil && isEmptyGoApi(goApi) {
+				continue
+			}
+			goApi.Package = *opts.pkgName
+			if gCurPackage != nil {
+				goApi.Package = gCurPackage.Name
+			}
+			if _, ok := goApiMap[api.Name]; !ok {
+				goApiMap[api.Name] = make([]*gomodel.GoApi, len(arches))
+				nsNames = append(nsNames, api.Name)
+			}
+			goApiMap[api.Name][n] = goApi
+		}
+	}
+	checkRoots(roots)
+	if len(nsNames) == 0 {
+		return errors.New("no namespace matched")
+	}
+
+	err = os.MkdirAll(*opts.outDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	//output dir -> dlls referenced by the package
+	pkgDlls := make(map[string]map[string]bool)
+	pkgNames := make(map[string]string)
+	//output dir -> whether the package has Owned handles
+	pkgLeakChecks := make(map[string]bool)
+	//several arches: the common files are constrained to them, so that the package does not
+	//build without its arch specific declarations on the others, e.g. arm
+	var commonTag string
+	if len(arches) > 1 {
+		var goArches []string
+		for _, arch := range arches {
+			goArches = append(goArches, arch.GoArch)
+		}
+		commonTag = strings.Join(goArches, " || ")
+	}
+	for _, ns := range nsNames {
+		dir := *opts.outDir
+		if pkg := gPackages[ns]; pkg != nil {
+			dir = filepath.Join(dir, filepath.FromSlash(pkg.Dir))
+			err = os.MkdirAll(dir, os.ModePerm)
+			if err != nil {
+				return err
+			}
+		}
+		goApis := goApiMap[ns]
+		if pkgDlls[dir] == nil {
+			pkgDlls[dir] = make(map[string]bool)
+		}
+		for _, goApi := range goApis {
+			if goApi == nil {
+				continue
+			}
+			pkgNames[dir] = goApi.Package
+			for _, f := range goApi.Funcs {
+				pkgDlls[dir][f.Dll] = true
+			}
+			if codegen.HasOwnedHandles(goApi) {
+				pkgLeakChecks[dir] = true
+			}
+		}
+		if *opts.layoutTests {
+			for n, arch := range arches {
+				if goApis[n] == nil {
+					continue
+				}
+				utils.SetArch(arch)
+				err = writeLayoutTest(goApis[n], filepath.Join(dir, ns+"_"+arch.GoArch+"_test.go"))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(arches) == 1 {
+			if err := writeGoApi(goApis[0], filepath.Join(dir, ns+".go")); err != nil {
+				return err
+			}
+			continue
+		}
+		common, archApis := splitByArch(arches, goApis)
+		//common declarations are those of the first arch
+		utils.SetArch(arches[0])
+		common.BuildTag = commonTag
+		if err := writeGoApi(common, filepath.Join(dir, ns+".go")); err != nil {
+			return err
+		}
+		for n, arch := range arches {
+			if archApis[n] == nil {
+				continue
+			}
+			utils.SetArch(arch)
+			archApis[n].BuildTag = arch.GoArch
+			err = writeGoApi(archApis[n], filepath.Join(dir, ns+"_"+arch.GoArch+".go"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for dir, dllSet := range pkgDlls {
+		var dlls []string
+		for dll := range dllSet {
+			dlls = append(dlls, dll)
+		}
+		w := bytes.NewBuffer(nil)
+		codegen.GenRuntime(pkgNames[dir], dlls, pkgLeakChecks[dir], commonTag, w)
+		if err := writeGoFile(filepath.Join(dir, codegen.RuntimeFileName), w.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	diag.PrintSummary(os.Stderr)
+	if diag.HasErrors() {
+		return errSkipped
+	}
 	println("Done.")
+	return nil
+}
+
+func writeLayoutTest(goApi *gomodel.GoApi, filePath string) error {
+	w := bytes.NewBuffer(nil)
+	if codegen.GenLayoutTest(goApi, w) {
+		return writeGoFile(filePath, w.Bytes())
+	}
+	return nil
+}
+
+func writeGoApi(goApi *gomodel.GoApi, filePath string) error {
+	w := bytes.NewBuffer(nil)
+	codegen.Gen(goApi, w)
+	return writeGoFile(filePath, w.Bytes())
 }
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go-win32api-gen/codegen"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/utils"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the want files of the golden tests")
+
+// TestGolden generates every testdata/golden/<case>/api directory
+// and compares the output with testdata/golden/<case>/want
+func TestGolden(t *testing.T) {
+	caseDirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, caseDir := range caseDirs {
+		caseDir := caseDir
+		t.Run(filepath.Base(caseDir), func(t *testing.T) {
+			outDir := t.TempDir()
+			flags := readFlags(t, caseDir)
+			args := []string{"-in", filepath.Join(caseDir, "api"), "-out", outDir}
+			testGenerate(t, append(args, flags...))
+			vetGolden(t, outDir, flags)
+
+			wantDir := filepath.Join(caseDir, "want")
+			if *update {
+				updateGolden(t, outDir, wantDir)
+				return
+			}
+			compareGolden(t, outDir, wantDir)
+		})
+	}
+}
+
+// parseGenFlags parses the gen flags in args
+func parseGenFlags(t *testing.T, args []string) *genFlags {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	opts := newGenFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+// testGenerate generates the bindings as the gen command does with args
+func testGenerate(t *testing.T, args []string) {
+	t.Helper()
+	if err := generate(parseGenFlags(t, args)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFlags returns the generator flags in the optional flags file of a case
+func readFlags(t *testing.T, caseDir string) []string {
+	content, err := ioutil.ReadFile(filepath.Join(caseDir, "flags"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(content))
+}
+
+// vetGolden runs go vet on the output of a case for windows on every arch it is generated for,
+// so that the expected output is known to compile
+func vetGolden(t *testing.T, outDir string, flags []string) {
+	if testing.Short() {
+		return
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Log("not vetting the output: ", err)
+		return
+	}
+	arches := utils.Arches[:1]
+	for n, flag := range flags {
+		if flag == "-arch" && n+1 < len(flags) {
+			arches, err = utils.ParseArches(flags[n+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err = ioutil.WriteFile(filepath.Join(outDir, "go.mod"), []byte("module win32\n\ngo 1.17\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arch := range arches {
+		cmd := exec.Command(goTool, "vet", ".")
+		cmd.Dir = outDir
+		cmd.Env = append(os.Environ(), "GOOS=windows", "GOARCH="+arch.GoArch, "GOFLAGS=", "GOWORK=off")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("go vet for %s failed: %v\n%s", arch.GoArch, err, out)
+		}
+	}
+}
+
+// readGoFiles reads the go files of a case output but runtime.go,
+// which is the same in every case but for the dlls and is compared by TestRuntime
+func readGoFiles(t *testing.T, dir string) map[string][]byte {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		if filepath.Base(path) == codegen.RuntimeFileName {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(path)] = content
+	}
+	return files
+}
+
+func updateGolden(t *testing.T, outDir string, wantDir string) {
+	if err := os.RemoveAll(wantDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(wantDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range readGoFiles(t, outDir) {
+		err := ioutil.WriteFile(filepath.Join(wantDir, name), content, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func compareGolden(t *testing.T, outDir string, wantDir string) {
+	got := readGoFiles(t, outDir)
+	want := readGoFiles(t, wantDir)
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		gotContent, ok := got[name]
+		if !ok {
+			t.Errorf("%s is not generated", name)
+			continue
+		}
+		wantContent, ok := want[name]
+		if !ok {
+			t.Errorf("%s is generated but not expected", name)
+			continue
+		}
+		if !bytes.Equal(gotContent, wantContent) {
+			line, gotLine, wantLine := firstDiff(string(gotContent), string(wantContent))
+			t.Errorf("%s differs at line %d:\n got: %s\nwant: %s",
+				filepath.Join(wantDir, name), line, gotLine, wantLine)
+		}
+	}
+	if t.Failed() {
+		t.Log("run go test -run TestGolden -update to accept the new output")
+	}
+}
+
+// firstDiff returns the number and contents of the first line that differs
+func firstDiff(got string, want string) (int, string, string) {
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for n := 0; ; n++ {
+		var gotLine, wantLine string
+		if n < len(gotLines) {
+			gotLine = gotLines[n]
+		}
+		if n < len(wantLines) {
+			wantLine = wantLines[n]
+		}
+		if gotLine != wantLine || n >= len(gotLines) || n >= len(wantLines) {
+			return n + 1, gotLine, wantLine
+		}
+	}
+}
+
+// TestRuntime compares the runtime.go all cases generate with testdata/runtime/want,
+// for a constrained package with two dlls and leak checks
+func TestRuntime(t *testing.T) {
+	var w bytes.Buffer
+	codegen.GenRuntime("win32", []string{"USER32", "KERNEL32"}, true, "amd64 || 386", &w)
+	wantFile := filepath.Join("testdata", "runtime", "want", codegen.RuntimeFileName)
+	got, err := formatGoFile(wantFile, w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(wantFile), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(wantFile, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(wantFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		line, gotLine, wantLine := firstDiff(string(got), string(want))
+		t.Errorf("%s differs at line %d:\n got: %s\nwant: %s", wantFile, line, gotLine, wantLine)
+	}
+}
+
+// TestSplit generates a package per namespace of the apiref case without the excluded Foundation,
+// which Test references
+func TestSplit(t *testing.T) {
+	var out bytes.Buffer
+	diag.Output = &out
+	defer func() {
+		diag.Output = os.Stderr
+	}()
+	outDir := t.TempDir()
+	testGenerate(t, []string{"-in", filepath.Join("testdata", "golden", "apiref", "api"), "-out", outDir,
+		"-split", "-module", "win32", "-exclude", "Foundation"})
+
+	if _, err := os.Stat(filepath.Join(outDir, "test", "Test.go")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "foundation")); !os.IsNotExist(err) {
+		t.Error("the excluded Foundation is planned a package")
+	}
+	want := "Test: warning: references Foundation, which is not generated"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("the reference to Foundation is not reported:\n%s", out.String())
+	}
+}
+
+// TestExclude generates the apiref case in a single package without the excluded Foundation,
+// the references to its declarations are reported since the package does not compile
+func TestExclude(t *testing.T) {
+	var out bytes.Buffer
+	diag.Output = &out
+	defer func() {
+		diag.Output = os.Stderr
+	}()
+	testGenerate(t, []string{"-in", filepath.Join("testdata", "golden", "apiref", "api"), "-out", t.TempDir(),
+		"-exclude", "Foundation"})
+
+	want := "Test: warning: references Foundation, which is not generated, " +
+		"its declarations are undefined in the package"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("the reference to Foundation is not reported:\n%s", out.String())
+	}
+}
+
+// TestRootAlias checks that a root only names the W declaration when the metadata declares it
+// as the unicode alias, WIDGET_NE does not name the WIDGET_NEW constant of the closure case
+func TestRootAlias(t *testing.T) {
+	var out bytes.Buffer
+	diag.Output = &out
+	defer func() {
+		diag.Output = os.Stderr
+	}()
+	err := generate(parseGenFlags(t, []string{"-in", filepath.Join("testdata", "golden", "closure", "api"),
+		"-out", t.TempDir(), "-roots", "WIDGET_NE"}))
+	if err == nil {
+		t.Fatal("the root WIDGET_NE matches WIDGET_NEW")
+	}
+	want := "WIDGET_NE: error: the root symbol is not declared"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("the unmatched root is not reported:\n%s", out.String())
+	}
+}
+
+// TestComClassWarnings checks that the helper of a class without an interface of its own is reported
+// returning IUnknown, and the helpers skipped without System.Com.CoCreateInstance are reported
+func TestComClassWarnings(t *testing.T) {
+	var out bytes.Buffer
+	diag.Output = &out
+	defer func() {
+		diag.Output = os.Stderr
+	}()
+	testGenerate(t, []string{"-in", filepath.Join("testdata", "golden", "comclass", "api"), "-out", t.TempDir()})
+	testGenerate(t, []string{"-winmd", filepath.Join("winmd", "testdata", "fixture.winmd"), "-out", t.TempDir()})
+
+	for _, want := range []string{
+		"UI.Shell.FileOperation: warning: CoCreateFileOperation returns IUnknown",
+		"UI.Widgets.Widget: warning: CoCreateWidget is not generated",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%q is not reported:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestGuidExpr checks that GUID literals are keyed, as go vet requires, and hold the GUID
+func TestGuidExpr(t *testing.T) {
+	src := utils.BuildGuidExpr("6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6001")
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, elt := range expr.(*ast.CompositeLit).Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			t.Fatalf("unkeyed field in %s", src)
+		}
+		keys = append(keys, kv.Key.(*ast.Ident).Name)
+	}
+	if strings.Join(keys, ",") != "Data1,Data2,Data3,Data4" {
+		t.Errorf("fields %v in %s", keys, src)
+	}
+	want := "syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,\n" +
+		"\tData4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x01}}"
+	if src != want {
+		t.Errorf("got %s\nwant %s", src, want)
+	}
+}
+
+// TestPrune regenerates the closure case with only the declarations a go file uses
+func TestPrune(t *testing.T) {
+	apiDir := filepath.Join("testdata", "golden", "closure", "api")
+	srcDir := t.TempDir()
+	outDir := filepath.Join(srcDir, "win32")
+	testGenerate(t, []string{"-in", apiDir, "-out", outDir})
+
+	src := "package main\n\nimport \"app/win32\"\n\nfunc main() {\n" +
+		"\tvar info win32.WIDGET_INFO\n\twin32.GetWidgetInfo(0, &info)\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	//a file without the generated header is kept, even named after a namespace
+	ownFile := filepath.Join(outDir, "Test_arm64.go")
+	own := "package win32\n\nfunc OwnHelper() {}\n"
+	if err := ioutil.WriteFile(ownFile, []byte(own), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := prune(parseGenFlags(t, []string{"-in", apiDir, "-out", outDir}), srcDir, "app/win32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idents := declaredIdents(outDir)
+	if content, err := ioutil.ReadFile(ownFile); err != nil || string(content) != own {
+		t.Errorf("%s is not kept: %v", ownFile, err)
+	}
+	for _, name := range []string{"GetWidgetInfo", "WIDGET_INFO", "HWIDGET", "PSTR"} {
+		if !idents[name] {
+			t.Errorf("%s is used but not generated", name)
+		}
+	}
+	for _, name := range []string{"GetWidgetName", "GADGET_INFO", "WIDGET_NONE", "WIDGET_MAX"} {
+		if idents[name] {
+			t.Errorf("%s is not used but generated", name)
+		}
+	}
+	//the bindings are generated in a temporary directory next to them
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "main.go" && name != "win32" {
+			t.Errorf("%s is left in %s", name, srcDir)
+		}
+	}
+}
+
+// TestDiff compares the metadata of testdata/diff/old and new on two arches
+func TestDiff(t *testing.T) {
+	arches, err := utils.ParseArches("X64,X86")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diffMetadata(filepath.Join("testdata", "diff", "old"), filepath.Join("testdata", "diff", "new"),
+		arches, &nsFilter{})
+	var text, js bytes.Buffer
+	if err := writeDiff(&text, changes, len(arches)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeDiffJson(&js, changes); err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string][]byte{"want.txt": text.Bytes(), "want.json": js.Bytes()} {
+		wantFile := filepath.Join("testdata", "diff", name)
+		if *update {
+			if err := ioutil.WriteFile(wantFile, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(wantFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs:\n%s", name, got)
+		}
+	}
+}
+
+// TestApiCheck compares the bindings generated from testdata/diff/old and new on two arches
+func TestApiCheck(t *testing.T) {
+	arches, err := utils.ParseArches("X64,X86")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outDir := t.TempDir()
+	for _, name := range []string{"old", "new"} {
+		testGenerate(t, []string{"-in", filepath.Join("testdata", "diff", name), "-out", filepath.Join(outDir, name),
+			"-arch", "X64,X86"})
+	}
+	found, err := outputArches(filepath.Join(outDir, "old"), filepath.Join(outDir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, arches) {
+		t.Errorf("the outputs are for %v, want %v", found, arches)
+	}
+	goos, goarch := build.Default.GOOS, build.Default.GOARCH
+	changes, err := checkGoApi(filepath.Join(outDir, "old"), filepath.Join(outDir, "new"), "", arches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.Default.GOOS != goos || build.Default.GOARCH != goarch {
+		t.Errorf("build.Default changed to %s/%s", build.Default.GOOS, build.Default.GOARCH)
+	}
+	var got bytes.Buffer
+	if err := writeApiCheck(&got, changes, len(arches)); err != nil {
+		t.Fatal(err)
+	}
+	wantFile := filepath.Join("testdata", "diff", "want-apicheck.txt")
+	if *update {
+		if err := ioutil.WriteFile(wantFile, got.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(wantFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("the changes differ:\n%s", got.Bytes())
+	}
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/jsonmodel"
+	"go-win32api-gen/utils"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metaDecl is a declaration of a metadata snapshot, as compared by the diff command
+type metaDecl struct {
+	Kind string //function, struct, union, enum, constant, or the type kind
+
+	//functions and methods by name for com interfaces
+	Signature metaSignature
+	Methods   []string
+	MethodSig map[string]metaSignature
+
+	//structs and unions, -1 if the size is unknown
+	Size   int
+	Align  int
+	Fields []metaField
+
+	//enums, values by name in declaration order
+	ValueNames []string
+	Values     map[string]string
+
+	//the type and value of constants, the base type of enums, the type of native typedefs,
+	//the base interface of com interfaces and the guid of com classes
+	Value string
+}
+
+type metaSignature struct {
+	Dll          string
+	SetLastError bool
+	ReturnType   string
+	Params       []string //name type [attrs]
+}
+
+type metaField struct {
+	Name   string
+	Type   string
+	Offset int //-1 for union fields and unknown layouts
+}
+
+// metaChange is a difference between two metadata snapshots
+type metaChange struct {
+	Change  string   `json:"change"` //added, removed, changed or renamed
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	NewName string   `json:"newName,omitempty"`
+	Details []string `json:"details,omitempty"`
+	Arches  []string `json:"arches"`
+}
+
+// runDiff reports the differences between the declarations of two metadata directories
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	archList := fs.String("arch", "X64", "comma separated architectures to compare: X64, X86, Arm64")
+	include := fs.String("include", "", "comma separated namespace globs to compare")
+	exclude := fs.String("exclude", "", "comma separated namespace globs to skip")
+	configFile := fs.String("config", "", "generator configuration the metadata is loaded with")
+	jsonOutput := fs.Bool("json", false, "write the changes as json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-win32api-gen diff [flags] old/api new/api")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *configFile != "" {
+		c, err := config.Load(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Cur = c
+	}
+	filter, err := newNsFilter(*include, *exclude)
+	if err != nil {
+		log.Fatal(err)
+	}
+	arches, err := utils.ParseArches(*archList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes := diffMetadata(fs.Arg(0), fs.Arg(1), arches, filter)
+	if *jsonOutput {
+		err = writeDiffJson(os.Stdout, changes)
+	} else {
+		err = writeDiff(os.Stdout, changes, len(arches))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// diffMetadata compares the declarations of the two directories for each arch,
+// changes found for several arches are reported once
+func diffMetadata(oldDir string, newDir string, arches []utils.Arch, filter *nsFilter) []metaChange {
+	diag.Reset()
+	var changes []metaChange
+	index := make(map[string]int)
+	for _, arch := range arches {
+		utils.SetArch(arch)
+		oldDecls := loadMetaDecls(oldDir, filter)
+		newDecls := loadMetaDecls(newDir, filter)
+		for _, it := range diffMetaDecls(oldDecls, newDecls) {
+			key := strings.Join(append([]string{it.Change, it.Kind, it.Name, it.NewName}, it.Details...), "\n")
+			if n, ok := index[key]; ok {
+				changes[n].Arches = append(changes[n].Arches, arch.Name)
+				continue
+			}
+			index[key] = len(changes)
+			it.Arches = []string{arch.Name}
+			changes = append(changes, it)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// loadMetaDecls loads the metadata of dir for utils.CurArch, by fq name. Sizes are computed
+// right away, they depend on the type registry of the loaded metadata.
+func loadMetaDecls(dir string, filter *nsFilter) map[string]*metaDecl {
+	decls := make(map[string]*metaDecl)
+	for _, api := range jsonmodel.LoadApis(dir) {
+		if !filter.Match(api.Name) {
+			continue
+		}
+		for _, f := range api.Functions {
+			decls[api.Name+"."+f.Name] = &metaDecl{Kind: "function", Signature: metaFuncSignature(f)}
+		}
+		for _, c := range api.Constants {
+			decls[api.Name+"."+c.Name] = &metaDecl{
+				Kind:  "constant",
+				Value: metaTypeString(c.Type) + " = " + c.Value.String(),
+			}
+		}
+		for _, t := range api.Types {
+			collectMetaTypes(t, decls)
+		}
+	}
+	return decls
+}
+
+func collectMetaTypes(t *jsonmodel.Type, decls map[string]*metaDecl) {
+	decl := &metaDecl{Kind: strings.ToLower(t.Kind)}
+	switch t.Kind {
+	case "Struct", "Union":
+		decl.Size, decl.Align = -1, -1
+		var layouts []jsonmodel.FieldLayout
+		diag.Guard(t.Location(), func() {
+			decl.Size, decl.Align = t.GetSize()
+			if t.Kind == "Struct" {
+				layouts = t.GetFieldLayouts()
+			}
+		})
+		for n, f := range t.Fields {
+			field := metaField{Name: f.Name, Type: metaTypeString(f.Type), Offset: -1}
+			if n < len(layouts) {
+				field.Offset = layouts[n].Offset
+			}
+			decl.Fields = append(decl.Fields, field)
+		}
+	case "Enum":
+		decl.Values = make(map[string]string)
+		for _, v := range t.Values {
+			decl.ValueNames = append(decl.ValueNames, v.Name)
+			decl.Values[v.Name] = v.Value.String()
+		}
+		decl.Value = t.IntegerBase
+	case "Com":
+		decl.MethodSig = make(map[string]metaSignature)
+		for _, m := range t.Methods {
+			decl.Methods = append(decl.Methods, m.Name)
+			decl.MethodSig[m.Name] = metaFuncSignature(m)
+		}
+		if t.Interface != nil {
+			decl.Value = metaTypeString(t.Interface)
+		}
+	case "NativeTypedef":
+		decl.Value = metaTypeString(t.Def)
+	case "FunctionPointer":
+		f := &jsonmodel.Function{ReturnType: t.ReturnType, SetLastError: t.SetLastError}
+		for _, p := range t.Params {
+			f.Params = append(f.Params, jsonmodel.Param{Name: p.Name, Type: p.Type, Attrs: p.Attrs})
+		}
+		decl.Signature = metaFuncSignature(f)
+	case "ComClassID":
+		decl.Value = t.Guid
+	}
+	decls[t.FqName] = decl
+	for _, nt := range t.NestedTypes {
+		collectMetaTypes(nt, decls)
+	}
+}
+
+func metaFuncSignature(f *jsonmodel.Function) metaSignature {
+	sig := metaSignature{
+		Dll:          f.DllImport,
+		SetLastError: f.SetLastError,
+		ReturnType:   "Void",
+	}
+	if f.ReturnType != nil {
+		sig.ReturnType = metaTypeString(f.ReturnType)
+	}
+	for _, p := range f.Params {
+		param := p.Name + " " + metaTypeString(p.Type)
+		var attrs []string
+		for _, a := range p.Attrs {
+			attrs = append(attrs, metaAttrString(a))
+		}
+		if len(attrs) > 0 {
+			param += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		sig.Params = append(sig.Params, param)
+	}
+	return sig
+}
+
+// metaAttrString formats an attribute, those with properties as Kind(name: value, ...)
+func metaAttrString(a jsonmodel.Attr) string {
+	if a.Props == nil {
+		return a.Str
+	}
+	var props []string
+	for k, v := range a.Props {
+		if k != "Kind" {
+			props = append(props, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+	sort.Strings(props)
+	return fmt.Sprintf("%v(%s)", a.Props["Kind"], strings.Join(props, ", "))
+}
+
+// metaTypeString formats a type reference in C syntax, e.g. *PWSTR or [32]Char
+func metaTypeString(t *jsonmodel.Type) string {
+	switch t.Kind {
+	case "PointerTo", "LPArray":
+		return "*" + metaTypeString(t.Child)
+	case "Array":
+		return "[" + strconv.Itoa(t.Shape.Size) + "]" + metaTypeString(t.Child)
+	default:
+		return t.Name
+	}
+}
+
+// diffMetaDecls returns the changes from oldDecls to newDecls, a removed and an added constant
+// of the same namespace, type and value are reported as renamed
+func diffMetaDecls(oldDecls map[string]*metaDecl, newDecls map[string]*metaDecl) []metaChange {
+	var changes []metaChange
+	var removedConsts, addedConsts []string
+	for name, decl := range oldDecls {
+		newDecl, ok := newDecls[name]
+		switch {
+		case !ok && decl.Kind == "constant":
+			removedConsts = append(removedConsts, name)
+		case !ok:
+			changes = append(changes, metaChange{Change: "removed", Kind: decl.Kind, Name: name})
+		default:
+			if details := diffMetaDecl(decl, newDecl); len(details) > 0 {
+				changes = append(changes, metaChange{Change: "changed", Kind: newDecl.Kind, Name: name,
+					Details: details})
+			}
+		}
+	}
+	for name, decl := range newDecls {
+		if _, ok := oldDecls[name]; ok {
+			continue
+		}
+		if decl.Kind == "constant" {
+			addedConsts = append(addedConsts, name)
+		} else {
+			changes = append(changes, metaChange{Change: "added", Kind: decl.Kind, Name: name})
+		}
+	}
+
+	//namespace and value -> added constants
+	constKey := func(name string, decl *metaDecl) string {
+		return name[:strings.LastIndexByte(name, '.')] + " " + decl.Value
+	}
+	added := make(map[string][]string)
+	for _, name := range addedConsts {
+		key := constKey(name, newDecls[name])
+		added[key] = append(added[key], name)
+	}
+	removed := make(map[string][]string)
+	for _, name := range removedConsts {
+		key := constKey(name, oldDecls[name])
+		removed[key] = append(removed[key], name)
+	}
+	renamed := make(map[string]bool)
+	for key, names := range removed {
+		//only unambiguous renames
+		if len(names) == 1 && len(added[key]) == 1 {
+			newName := added[key][0]
+			changes = append(changes, metaChange{Change: "renamed", Kind: "constant", Name: names[0],
+				NewName: newName[strings.LastIndexByte(newName, '.')+1:]})
+			renamed[names[0]], renamed[newName] = true, true
+		}
+	}
+	for _, name := range removedConsts {
+		if !renamed[name] {
+			changes = append(changes, metaChange{Change: "removed", Kind: "constant", Name: name})
+		}
+	}
+	for _, name := range addedConsts {
+		if !renamed[name] {
+			changes = append(changes, metaChange{Change: "added", Kind: "constant", Name: name})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// diffMetaDecl describes the changes of a declaration
+func diffMetaDecl(from *metaDecl, to *metaDecl) []string {
+	var details []string
+	changed := func(what string, fromValue string, toValue string) {
+		if fromValue != toValue {
+			details = append(details, fmt.Sprintf("%s: %s -> %s", what, fromValue, toValue))
+		}
+	}
+	if from.Kind != to.Kind {
+		changed("kind", from.Kind, to.Kind)
+		return details
+	}
+	switch to.Kind {
+	case "function", "functionpointer":
+		details = append(details, diffMetaSignature("", from.Signature, to.Signature)...)
+	case "struct", "union":
+		changed("size", metaSize(from.Size), metaSize(to.Size))
+		changed("alignment", metaSize(from.Align), metaSize(to.Align))
+		details = append(details, diffMetaFields(from.Fields, to.Fields)...)
+	case "enum":
+		changed("base type", from.Value, to.Value)
+		for _, name := range from.ValueNames {
+			if v, ok := to.Values[name]; !ok {
+				details = append(details, "value removed: "+name)
+			} else {
+				changed("value "+name, from.Values[name], v)
+			}
+		}
+		for _, name := range to.ValueNames {
+			if _, ok := from.Values[name]; !ok {
+				details = append(details, "value added: "+name+" = "+to.Values[name])
+			}
+		}
+	case "com":
+		changed("base interface", from.Value, to.Value)
+		changed("methods", strings.Join(from.Methods, ", "), strings.Join(to.Methods, ", "))
+		for _, name := range to.Methods {
+			if fromSig, ok := from.MethodSig[name]; ok {
+				details = append(details, diffMetaSignature(name+" ", fromSig, to.MethodSig[name])...)
+			}
+		}
+	default:
+		changed("value", from.Value, to.Value)
+	}
+	return details
+}
+
+func metaSize(size int) string {
+	if size < 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(size)
+}
+
+func diffMetaSignature(prefix string, from metaSignature, to metaSignature) []string {
+	var details []string
+	changed := func(what string, fromValue string, toValue string) {
+		if fromValue != toValue {
+			details = append(details, fmt.Sprintf("%s%s: %s -> %s", prefix, what, fromValue, toValue))
+		}
+	}
+	changed("dll", from.Dll, to.Dll)
+	changed("SetLastError", strconv.FormatBool(from.SetLastError), strconv.FormatBool(to.SetLastError))
+	changed("return type", from.ReturnType, to.ReturnType)
+	for n := 0; n < len(from.Params) || n < len(to.Params); n++ {
+		what := "param " + strconv.Itoa(n)
+		switch {
+		case n >= len(to.Params):
+			details = append(details, prefix+what+" removed: "+from.Params[n])
+		case n >= len(from.Params):
+			details = append(details, prefix+what+" added: "+to.Params[n])
+		default:
+			changed(what, from.Params[n], to.Params[n])
+		}
+	}
+	return details
+}
+
+func diffMetaFields(from []metaField, to []metaField) []string {
+	var details []string
+	fromFields := make(map[string]metaField)
+	for _, f := range from {
+		fromFields[f.Name] = f
+	}
+	toFields := make(map[string]bool)
+	for _, f := range to {
+		toFields[f.Name] = true
+		fromField, ok := fromFields[f.Name]
+		if !ok {
+			details = append(details, "field added: "+f.Name+" "+f.Type+metaOffset(f.Offset))
+			continue
+		}
+		if fromField.Type != f.Type {
+			details = append(details, fmt.Sprintf("field %s: %s -> %s", f.Name, fromField.Type, f.Type))
+		}
+		if fromField.Offset != f.Offset {
+			details = append(details, fmt.Sprintf("field %s: offset %s -> %s", f.Name,
+				metaSize(fromField.Offset), metaSize(f.Offset)))
+		}
+	}
+	for _, f := range from {
+		if !toFields[f.Name] {
+			details = append(details, "field removed: "+f.Name+" "+f.Type)
+		}
+	}
+	return details
+}
+
+func metaOffset(offset int) string {
+	if offset < 0 {
+		return ""
+	}
+	return " at " + strconv.Itoa(offset)
+}
+
+// writeDiff writes the changes for people, with the arches of those that are not found
+// for all archCount arches, followed by a summary
+func writeDiff(w io.Writer, changes []metaChange, archCount int) error {
+	marks := map[string]string{"added": "+", "removed": "-", "changed": "~", "renamed": ">"}
+	counts := make(map[string]int)
+	for _, it := range changes {
+		counts[it.Change]++
+		line := marks[it.Change] + " " + it.Kind + " " + it.Name
+		if it.NewName != "" {
+			line += " -> " + it.NewName
+		}
+		if len(it.Arches) < archCount {
+			line += " (" + strings.Join(it.Arches, ", ") + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, detail := range it.Details {
+			fmt.Fprintln(w, "    "+detail)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d removed, %d changed, %d renamed\n",
+		counts["added"], counts["removed"], counts["changed"], counts["renamed"])
+	return err
+}
+
+func writeDiffJson(w io.Writer, changes []metaChange) error {
+	if changes == nil {
+		changes = []metaChange{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// namespace filter using glob patterns like "UI.*" or "System.Com"
+type nsFilter struct {
+	includes []string
+	excludes []string
+}
+
+func splitPatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		//validate the pattern
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func newNsFilter(include, exclude string) (*nsFilter, error) {
+	includes, err := splitPatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := splitPatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &nsFilter{includes: includes, excludes: excludes}, nil
+}
+
+func matchAny(patterns []string, ns string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, ns); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *nsFilter) Match(ns string) bool {
+	if len(this.includes) > 0 && !matchAny(this.includes, ns) {
+		return false
+	}
+	return !matchAny(this.excludes, ns)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/jsonmodel"
+	"path"
+	"sort"
+	"strings"
+)
+
+// goPackage is an output package holding one namespace,
+// or several namespaces when their imports form a cycle
+type goPackage struct {
+	Name       string
+	Dir        string
+	ImportPath string
+	Namespaces []string
+}
+
+// namespace -> package, nil unless generating a package per namespace
+var gPackages map[string]*goPackage
+
+// package of the api being built
+var gCurPackage *goPackage
+
+// collectNsDeps adds the namespaces referenced by each api the filter matches to deps
+func collectNsDeps(apis []*jsonmodel.Api, filter *nsFilter, deps map[string]map[string]bool) {
+	for _, api := range apis {
+		if !filter.Match(api.Name) {
+			continue
+		}
+		set := deps[api.Name]
+		if set == nil {
+			set = make(map[string]bool)
+			deps[api.Name] = set
+		}
+		api.VisitTypeRefs(func(t *jsonmodel.Type) {
+			if t.Kind != "ApiRef" || t.Api == api.Name {
+				return
+			}
+			if _, ok := config.Cur.TypeOverride(t.Name); ok {
+				return
+			}
+			set[t.Api] = true
+		})
+	}
+}
+
+// findCycles returns the strongly connected components with more than one namespace
+func findCycles(deps map[string]map[string]bool) [][]string {
+	var names []string
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		lowLink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		var targets []string
+		for w := range deps[v] {
+			if _, ok := deps[w]; ok {
+				targets = append(targets, w)
+			}
+		}
+		sort.Strings(targets)
+		for _, w := range targets {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				if lowLink[w] < lowLink[v] {
+					lowLink[v] = lowLink[w]
+				}
+			} else if onStack[w] && index[w] < lowLink[v] {
+				lowLink[v] = index[w]
+			}
+		}
+		if lowLink[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Strings(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	for _, name := range names {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+// planPackages assigns a package to each namespace of deps,
+// namespaces importing each other in a cycle are merged into one package
+func planPackages(deps map[string]map[string]bool, module string) map[string]*goPackage {
+	packages := make(map[string]*goPackage)
+	for ns := range deps {
+		packages[ns] = &goPackage{Namespaces: []string{ns}}
+	}
+	for _, cycle := range findCycles(deps) {
+		//the namespace referenced most by the others names the package
+		mainNs := cycle[0]
+		maxRefs := -1
+		for _, ns := range cycle {
+			refs := 0
+			for _, other := range cycle {
+				if deps[other][ns] {
+					refs++
+				}
+			}
+			if refs > maxRefs {
+				mainNs, maxRefs = ns, refs
+			}
+		}
+		pkg := &goPackage{Namespaces: []string{mainNs}}
+		for _, ns := range cycle {
+			if ns != mainNs {
+				pkg.Namespaces = append(pkg.Namespaces, ns)
+			}
+			packages[ns] = pkg
+		}
+		diag.Warn(diag.Location{Name: mainNs}, "import cycle between %s, merged into one package",
+			strings.Join(cycle, ", "))
+	}
+
+	var nss []string
+	for ns := range packages {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+
+	//package names are made unique so that no import alias is needed
+	nameCounts := make(map[string]int)
+	for _, ns := range nss {
+		if pkg := packages[ns]; pkg.Namespaces[0] == ns {
+			nameCounts[lastNsPart(ns)]++
+		}
+	}
+	for _, ns := range nss {
+		pkg := packages[ns]
+		if pkg.Namespaces[0] != ns {
+			continue
+		}
+		pkg.Name = lastNsPart(ns)
+		if nameCounts[pkg.Name] > 1 {
+			pkg.Name = strings.ToLower(strings.ReplaceAll(ns, ".", ""))
+		}
+		pkg.Dir = strings.ToLower(strings.ReplaceAll(ns, ".", "/"))
+		pkg.ImportPath = path.Join(module, pkg.Dir)
+	}
+	return packages
+}
+
+// reportMissingNamespaces warns about the references into namespaces that are not generated,
+// filtered out or not in the metadata: their declarations are left unqualified with split,
+// and are undefined in the single package otherwise
+func reportMissingNamespaces(deps map[string]map[string]bool, split bool) {
+	consequence := "its declarations are undefined in the package"
+	if split {
+		consequence = "its declarations are left unqualified"
+	}
+	var nss []string
+	for ns := range deps {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	for _, ns := range nss {
+		var missing []string
+		for target := range deps[ns] {
+			if _, ok := deps[target]; !ok {
+				missing = append(missing, target)
+			}
+		}
+		sort.Strings(missing)
+		for _, target := range missing {
+			diag.Warn(diag.Location{Name: ns}, "references %s, which is not generated, %s",
+				target, consequence)
+		}
+	}
+}
+
+func lastNsPart(ns string) string {
+	return strings.ToLower(ns[strings.LastIndexByte(ns, '.')+1:])
+}
+
+// qualifyName returns the go name of a type declared in namespace ns
+// as seen from the package of the api being built
+func qualifyName(ns string, name string) string {
+	if gPackages == nil {
+		return name
+	}
+	//references into namespaces that are not generated are reported by generate
+	pkg, ok := gPackages[ns]
+	if !ok {
+		return name
+	}
+	if pkg == gCurPackage {
+		return name
+	}
+	return pkg.Name + "." + name
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"go-win32api-gen/config"
+	"go-win32api-gen/diag"
+	"go-win32api-gen/jsonmodel"
+	"go-win32api-gen/utils"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// runPrune regenerates the bindings with only the symbols a go module uses, and their
+// dependencies. It takes the gen flags, those the bindings were generated with.
+func runPrune(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	opts := newGenFlags(fs)
+	srcDir := fs.String("src", ".", "directory of the go module using the generated bindings")
+	importPath := fs.String("import", "",
+		"import path of the generated package, the -module of the bindings by default")
+	fs.Parse(args)
+
+	if err := prune(opts, *srcDir, *importPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// prune regenerates the bindings of opts with only the symbols the go packages under srcDir
+// use from the generated package imp, or -module when imp is ""
+func prune(opts *genFlags, srcDir string, imp string) error {
+	if imp == "" {
+		imp = *opts.module
+	}
+	if imp == "" {
+		return errors.New("-import or -module is required")
+	}
+	isGenerated := func(importPath string) bool {
+		return importPath == imp || *opts.split && strings.HasPrefix(importPath, imp+"/")
+	}
+
+	outDir, err := filepath.Abs(*opts.outDir)
+	if err != nil {
+		return err
+	}
+	uses, err := scanUses(srcDir, outDir, imp, isGenerated)
+	if err != nil {
+		return err
+	}
+
+	if *opts.configFile != "" {
+		c, err := config.Load(*opts.configFile)
+		if err != nil {
+			return err
+		}
+		config.Cur = c
+	}
+	arches, err := utils.ParseArches(*opts.archList)
+	if err != nil {
+		return err
+	}
+	declared := make(map[string]string)
+	nsSet := make(map[string]bool)
+	for _, arch := range arches {
+		utils.SetArch(arch)
+		apis, err := opts.loadApis()
+		if err != nil {
+			return err
+		}
+		for _, api := range apis {
+			nsSet[api.Name] = true
+			collectGoNames(api, declared)
+		}
+	}
+
+	var roots []string
+	if *opts.rootList != "" {
+		roots, err = parseRoots(*opts.rootList)
+		if err != nil {
+			return err
+		}
+	}
+	rootSet := make(map[string]bool)
+	addRoot := func(root string) {
+		if !rootSet[root] {
+			rootSet[root] = true
+			roots = append(roots, root)
+		}
+	}
+	var usedNames []string
+	for name := range uses {
+		usedNames = append(usedNames, name)
+		root := rootOf(name, declared)
+		if root == "" {
+			continue
+		}
+		addRoot(root)
+		if strings.HasPrefix(name, "CoCreate") && root != "CoCreateInstance" {
+			//CoCreateXxx calls CoCreateInstance
+			addRoot("CoCreateInstance")
+		}
+	}
+	sort.Strings(usedNames)
+	sort.Strings(roots)
+	if len(roots) == 0 {
+		return fmt.Errorf("no generated symbol is used in %s", srcDir)
+	}
+
+	//the bindings are generated in a copy of outDir next to it without the generated files,
+	//which replaces outDir only on success
+	if err := os.MkdirAll(filepath.Dir(outDir), os.ModePerm); err != nil {
+		return err
+	}
+	stageDir, err := os.MkdirTemp(filepath.Dir(outDir), "."+filepath.Base(outDir)+"-prune-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+	if err := copyFiles(outDir, stageDir); err != nil {
+		return err
+	}
+	if err := removeGeneratedFiles(stageDir, nsSet); err != nil {
+		return err
+	}
+	before := declaredIdents(outDir)
+	*opts.outDir = stageDir
+	*opts.rootList = strings.Join(roots, ",")
+	if err := generate(opts); err != nil {
+		return fmt.Errorf("%v, the bindings in %s are left unchanged", err, outDir)
+	}
+	if err := replaceDir(outDir, stageDir); err != nil {
+		return err
+	}
+	after := declaredIdents(outDir)
+
+	for _, name := range usedNames {
+		if !after[name] {
+			diag.Warn(diag.Location{Name: name}, "used in %s but not generated", srcDir)
+		}
+	}
+	var removed, added []string
+	for name := range before {
+		if !after[name] {
+			removed = append(removed, name)
+		}
+	}
+	for name := range after {
+		if !before[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	for _, name := range removed {
+		fmt.Println("-", name)
+	}
+	for _, name := range added {
+		fmt.Println("+", name)
+	}
+	fmt.Printf("%d used symbol(s), %d root(s), %d declaration(s) kept, %d removed, %d added\n",
+		len(usedNames), len(roots), len(after)-len(added), len(removed), len(added))
+	return nil
+}
+
+// scanUses returns the names the go packages under srcDir use from the generated packages.
+// The packages are type checked so that only selectors of the generated imports count,
+// imported packages are not loaded and the errors that follow are ignored.
+// The generated packages in outDir are skipped, they refer to each other.
+func scanUses(srcDir string, outDir string, imp string, isGenerated func(string) bool) (map[string]bool, error) {
+	importer := emptyImporter(generatedPackageNames(outDir, imp))
+
+	//dir -> package name -> files
+	fset := token.NewFileSet()
+	pkgFiles := make(map[string]map[string][]*ast.File)
+	err := filepath.Walk(srcDir, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			name := fi.Name()
+			absPath, _ := filepath.Abs(filePath)
+			if absPath == outDir || filePath != srcDir &&
+				(name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(filePath, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, filePath, nil, 0)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(filePath)
+		if pkgFiles[dir] == nil {
+			pkgFiles[dir] = make(map[string][]*ast.File)
+		}
+		pkgFiles[dir][file.Name.Name] = append(pkgFiles[dir][file.Name.Name], file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uses := make(map[string]bool)
+	for dir, pkgs := range pkgFiles {
+		for name, files := range pkgs {
+			conf := types.Config{Importer: importer, Error: func(error) {}}
+			info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+			conf.Check(dir+"/"+name, fset, files, info)
+			for _, file := range files {
+				ast.Inspect(file, func(n ast.Node) bool {
+					sel, ok := n.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					x, ok := sel.X.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					if pkgName, ok := info.Uses[x].(*types.PkgName); ok && isGenerated(pkgName.Imported().Path()) {
+						uses[sel.Sel.Name] = true
+					}
+					return true
+				})
+			}
+		}
+	}
+	return uses, nil
+}
+
+// emptyImporter imports packages without loading them, as empty packages named
+// as in the map or after the last element of their import path
+type emptyImporter map[string]string
+
+func (this emptyImporter) Import(importPath string) (*types.Package, error) {
+	name, ok := this[importPath]
+	if !ok {
+		name = path.Base(importPath)
+	}
+	pkg := types.NewPackage(importPath, name)
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// generatedPackageNames returns the names of the packages generated in outDir by import path
+func generatedPackageNames(outDir string, imp string) map[string]string {
+	names := make(map[string]string)
+	filepath.Walk(outDir, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !strings.HasSuffix(filePath, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(outDir, filepath.Dir(filePath))
+		names[path.Join(imp, filepath.ToSlash(rel))] = file.Name.Name
+		return nil
+	})
+	return names
+}
+
+// collectGoNames maps the go names of the declarations of api to their metadata names,
+// enum values to their enum
+func collectGoNames(api *jsonmodel.Api, declared map[string]string) {
+	for _, t := range api.Types {
+		declared[goName(t.Name)] = t.Name
+		for _, v := range t.Values {
+			declared[goName(v.Name)] = t.Name
+		}
+	}
+	for _, f := range api.Functions {
+		declared[goName(f.Name)] = f.Name
+	}
+	for _, c := range api.Constants {
+		declared[goName(c.Name)] = c.Name
+	}
+	for _, a := range api.UnicodeAliases {
+		if _, ok := declared[goName(a+"W")]; ok {
+			declared[goName(a)] = a + "W"
+		}
+	}
+}
+
+// prefixes and suffixes codegen adds to the names of the declarations it generates helpers for
+var (
+	helperPrefixes = []string{"New", "CoCreate", "IID_", "CLSID_", "Owned"}
+	helperSuffixes = []string{"Go", "Alloc", "Vtbl", "Interface", "Server", "ServerMethods"}
+)
+
+// rootOf returns the metadata name of the declaration the generated go name belongs to,
+// or "" for the names of the runtime support
+func rootOf(name string, declared map[string]string) string {
+	if root, ok := declared[name]; ok {
+		return root
+	}
+	for _, prefix := range helperPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			if root := rootOf(name[len(prefix):], declared); root != "" {
+				return root
+			}
+		}
+	}
+	for _, suffix := range helperSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			if root, ok := declared[name[:len(name)-len(suffix)]]; ok {
+				return root
+			}
+		}
+	}
+	//nested types are named after the types they are nested in
+	for n := strings.LastIndexByte(name, '_'); n > 0; n = strings.LastIndexByte(name[:n], '_') {
+		if root, ok := declared[name[:n]]; ok {
+			return root
+		}
+	}
+	return ""
+}
+
+// declaredIdents returns the exported package level identifiers of the go files under dir,
+// without the tests
+func declaredIdents(dir string) map[string]bool {
+	idents := make(map[string]bool)
+	filepath.Walk(dir, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !strings.HasSuffix(filePath, ".go") ||
+			strings.HasSuffix(filePath, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, 0)
+		if err != nil {
+			return nil
+		}
+		for name, obj := range file.Scope.Objects {
+			if ast.IsExported(name) && obj.Kind != ast.Bad {
+				idents[name] = true
+			}
+		}
+		return nil
+	})
+	return idents
+}
+
+// copyFiles copies the files under from to the same paths under to, a missing from has none
+func copyFiles(from string, to string) error {
+	err := filepath.Walk(from, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, filePath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, fi.Mode())
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// replaceDir replaces dir with the directory from. dir is moved aside first
+// and moved back if from cannot take its place.
+func replaceDir(dir string, from string) error {
+	old := ""
+	if _, err := os.Stat(dir); err == nil {
+		old = from + "-old"
+		if err := os.Rename(dir, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(from, dir); err != nil {
+		if old != "" {
+			if err := os.Rename(old, dir); err != nil {
+				return fmt.Errorf("%v, the previous bindings are left in %s", err, old)
+			}
+		}
+		return fmt.Errorf("%v, the bindings in %s are left unchanged", err, dir)
+	}
+	if old != "" {
+		return os.RemoveAll(old)
+	}
+	return nil
+}
+
+// removeGeneratedFiles removes the files generate writes under dir, so that the files
+// of namespaces no longer generated do not remain, and the directories left empty.
+// Only the files starting with the generated header are removed.
+func removeGeneratedFiles(dir string, nsSet map[string]bool) error {
+	var subDirs []string
+	err := filepath.Walk(dir, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if filePath != dir {
+				subDirs = append(subDirs, filePath)
+			}
+			return nil
+		}
+		name := strings.TrimSuffix(fi.Name(), ".go")
+		if name == fi.Name() {
+			return nil
+		}
+		name = strings.TrimSuffix(name, "_test")
+		for _, arch := range utils.Arches {
+			name = strings.TrimSuffix(name, "_"+arch.GoArch)
+		}
+		if !nsSet[name] && fi.Name() != "runtime.go" {
+			return nil
+		}
+		generated, err := hasGeneratedHeader(filePath)
+		if err != nil || !generated {
+			return err
+		}
+		return os.Remove(filePath)
+	})
+	if err != nil {
+		return err
+	}
+	//nested directories come last, removing fails for those that are not empty
+	for n := len(subDirs) - 1; n >= 0; n-- {
+		os.Remove(subDirs[n])
+	}
+	return nil
+}
+
+// hasGeneratedHeader reports whether the file starts with the header of the generated files
+func hasGeneratedHeader(filePath string) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return strings.TrimRight(line, "\r\n") == generatedHeader, nil
+}
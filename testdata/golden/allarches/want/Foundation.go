@@ -0,0 +1,45 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+//go:build amd64 || 386 || arm64
+
+package win32
+
+import (
+	"syscall"
+)
+
+type HANDLE = uintptr
+type HWND = uintptr
+type BOOL = int32
+type HRESULT = int32
+type PWSTR = *uint16
+
+const (
+	MAX_PATH          uint32  = 260
+	INVALID_FILE_SIZE int32   = -1
+	DEFAULT_NAME      string  = "wid\\get \"1\""
+	WIDGET_SCALE      float64 = 1.5
+	WIDGET_RATIO      float32 = 2
+	WIDGET_BIG        int64   = -9223372036854775808
+)
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	NO_ERROR            WIN32_ERROR = 0
+	ERROR_ACCESS_DENIED WIN32_ERROR = 5
+	WAIT_FAILED         WIN32_ERROR = 4294967295
+)
+
+var (
+	pCloseHandle uintptr
+)
+
+func CloseHandle(hObject HANDLE) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pCloseHandle, libKernel32, "CloseHandle")
+	ret, _, err := syscall.SyscallN(addr, hObject)
+	return BOOL(ret), WIN32_ERROR(err)
+}
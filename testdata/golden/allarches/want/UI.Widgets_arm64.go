@@ -0,0 +1,36 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+//go:build arm64
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// structs
+
+type WIDGET_INFO struct {
+	CbSize   uint32
+	Style    WIDGET_STYLE
+	Kind     WIDGET_KIND
+	_        [3]byte
+	Name     [8]byte
+	EnumProc [8]byte
+	Widget   [8]byte
+	WIDGET_INFO_Anonymous_
+}
+
+type WIDGET_CONTEXT struct {
+	Rip uint64
+}
+
+var (
+	pGetWidgetContext uintptr
+)
+
+func GetWidgetContext(hWidget HWIDGET, context *WIDGET_CONTEXT) {
+	addr := lazyAddr(&pGetWidgetContext, libUser32, "GetWidgetContext")
+	_, _, _ = syscall.SyscallN(addr, hWidget, uintptr(unsafe.Pointer(context)))
+}
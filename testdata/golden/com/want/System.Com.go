@@ -0,0 +1,251 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// coms
+
+// 00000000-0000-0000-c000-000000000046
+var IID_IUnknown = syscall.GUID{Data1: 0x00000000, Data2: 0x0000, Data3: 0x0000,
+	Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+
+type IUnknownInterface interface {
+	QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) HRESULT
+	AddRef() uint32
+	Release() uint32
+}
+
+type IUnknownVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+}
+
+type IUnknown struct {
+	LpVtbl *[1024]uintptr
+}
+
+func (this *IUnknown) Vtbl() *IUnknownVtbl {
+	return (*IUnknownVtbl)(unsafe.Pointer(this.LpVtbl))
+}
+
+func (this *IUnknown) QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().QueryInterface, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(riid)), uintptr(unsafe.Pointer(ppvObject)))
+	return HRESULT(ret)
+}
+
+func (this *IUnknown) AddRef() uint32 {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().AddRef, uintptr(unsafe.Pointer(this)))
+	return uint32(ret)
+}
+
+func (this *IUnknown) Release() uint32 {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().Release, uintptr(unsafe.Pointer(this)))
+	return uint32(ret)
+}
+
+// ComServer is a COM object implemented by a go value,
+// it is created by the NewXxxServer functions generated for each interface.
+// The object is kept alive until its reference count drops to zero.
+type ComServer struct {
+	LpVtbl *[1024]uintptr
+	refs   int32
+	impl   interface{}
+	iids   []*syscall.GUID
+}
+
+var (
+	comServerMu sync.Mutex
+	comServers  = make(map[*ComServer]bool)
+)
+
+// NewComServer creates a COM object with the vtable vtbl dispatching to impl,
+// answering QueryInterface for IUnknown and iids. Its reference count starts at 1.
+func NewComServer(vtbl []uintptr, impl interface{}, iids ...*syscall.GUID) *ComServer {
+	server := &ComServer{
+		LpVtbl: (*[1024]uintptr)(unsafe.Pointer(&vtbl[0])),
+		refs:   1,
+		impl:   impl,
+		iids:   iids,
+	}
+	comServerMu.Lock()
+	comServers[server] = true
+	comServerMu.Unlock()
+	return server
+}
+
+// ComServerOf returns the server object of a COM this pointer
+func ComServerOf(this unsafe.Pointer) *ComServer {
+	return (*ComServer)(this)
+}
+
+// Impl returns the go value implementing the object
+func (this *ComServer) Impl() interface{} {
+	return this.impl
+}
+
+func (this *ComServer) AddRef() uint32 {
+	return uint32(atomic.AddInt32(&this.refs, 1))
+}
+
+func (this *ComServer) Release() uint32 {
+	refs := atomic.AddInt32(&this.refs, -1)
+	if refs == 0 {
+		comServerMu.Lock()
+		delete(comServers, this)
+		comServerMu.Unlock()
+	}
+	return uint32(refs)
+}
+
+func (this *ComServer) queryInterface(riid *syscall.GUID, ppvObject *uintptr) uintptr {
+	if *riid == IID_IUnknown {
+		*ppvObject = uintptr(unsafe.Pointer(this))
+		this.AddRef()
+		return 0
+	}
+	for _, iid := range this.iids {
+		if *riid == *iid {
+			*ppvObject = uintptr(unsafe.Pointer(this))
+			this.AddRef()
+			return 0
+		}
+	}
+	*ppvObject = 0
+	return 0x80004002 //E_NOINTERFACE
+}
+
+var (
+	iUnknownServerOnce   sync.Once
+	iUnknownServerThunks []uintptr
+)
+
+// IUnknownServerMethods appends the IUnknown methods of ComServer to a vtable
+func IUnknownServerMethods(vtbl []uintptr) []uintptr {
+	iUnknownServerOnce.Do(func() {
+		iUnknownServerThunks = []uintptr{
+			syscall.NewCallback(func(this unsafe.Pointer, riid *syscall.GUID, ppvObject *uintptr) uintptr {
+				return ComServerOf(this).queryInterface(riid, ppvObject)
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).AddRef())
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).Release())
+			}),
+		}
+	})
+	return append(vtbl, iUnknownServerThunks...)
+}
+
+// ComServerUnknown is embedded in go implementations of COM interfaces
+// to satisfy IUnknownInterface, a server object answers IUnknown calls itself.
+type ComServerUnknown struct{}
+
+func (ComServerUnknown) QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) HRESULT {
+	var ret HRESULT
+	return ret
+}
+
+func (ComServerUnknown) AddRef() uint32 {
+	var ret uint32
+	return ret
+}
+
+func (ComServerUnknown) Release() uint32 {
+	var ret uint32
+	return ret
+}
+
+// 6f1a3a3c-7c1e-4b3e-9d8c-2b0e3c4d5e6f
+var IID_ICounter = syscall.GUID{Data1: 0x6f1a3a3c, Data2: 0x7c1e, Data3: 0x4b3e,
+	Data4: [8]byte{0x9d, 0x8c, 0x2b, 0x0e, 0x3c, 0x4d, 0x5e, 0x6f}}
+
+type ICounterInterface interface {
+	IUnknownInterface
+	Increment(step int32) HRESULT
+	GetValue(value *int32) HRESULT
+	SetBounds(bounds RECT) HRESULT
+	GetOrigin() POINT
+}
+
+type ICounterVtbl struct {
+	IUnknownVtbl
+	Increment uintptr
+	GetValue  uintptr
+	SetBounds uintptr
+	GetOrigin uintptr
+}
+
+type ICounter struct {
+	IUnknown
+}
+
+func (this *ICounter) Vtbl() *ICounterVtbl {
+	return (*ICounterVtbl)(unsafe.Pointer(this.IUnknown.LpVtbl))
+}
+
+func (this *ICounter) Increment(step int32) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().Increment, uintptr(unsafe.Pointer(this)), uintptr(step))
+	return HRESULT(ret)
+}
+
+func (this *ICounter) GetValue(value *int32) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetValue, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(value)))
+	return HRESULT(ret)
+}
+
+func (this *ICounter) SetBounds(bounds RECT) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().SetBounds, uintptr(unsafe.Pointer(this)), (uintptr)(unsafe.Pointer(&bounds)))
+	return HRESULT(ret)
+}
+
+func (this *ICounter) GetOrigin() POINT {
+	var ret POINT
+	_, _, _ = syscall.SyscallN(this.Vtbl().GetOrigin, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(&ret)))
+	return ret
+}
+
+// ICounterServerMethods appends the methods of ICounter implemented by the go value of a ComServer to a vtable
+func ICounterServerMethods(vtbl []uintptr) []uintptr {
+	vtbl = IUnknownServerMethods(vtbl)
+	return append(vtbl,
+		syscall.NewCallback(func(this unsafe.Pointer, a0 uintptr) uintptr {
+			impl := ComServerOf(this).Impl().(ICounterInterface)
+			ret := impl.Increment(int32(a0))
+			return uintptr(ret)
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *int32) uintptr {
+			impl := ComServerOf(this).Impl().(ICounterInterface)
+			ret := impl.GetValue(a0)
+			return uintptr(ret)
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 uintptr) uintptr {
+			return 0x80004001 //E_NOTIMPL
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+			return 0
+		}),
+	)
+}
+
+var (
+	iCounterServerOnce sync.Once
+	iCounterServerVtbl []uintptr
+)
+
+// NewICounterServer returns a COM object implementing ICounter by calling impl,
+// with a reference count of 1. impl usually embeds ComServerUnknown.
+func NewICounterServer(impl ICounterInterface) *ICounter {
+	iCounterServerOnce.Do(func() {
+		iCounterServerVtbl = ICounterServerMethods(nil)
+	})
+	server := NewComServer(iCounterServerVtbl, impl, &IID_ICounter)
+	return (*ICounter)(unsafe.Pointer(server))
+}
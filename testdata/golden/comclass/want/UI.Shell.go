@@ -0,0 +1,89 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// coms
+
+// 000214f9-0000-0000-c000-000000000046
+var IID_IShellLinkW = syscall.GUID{Data1: 0x000214f9, Data2: 0x0000, Data3: 0x0000,
+	Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+
+type IShellLinkWInterface interface {
+	IUnknownInterface
+	GetShowCmd(piShowCmd *int32) HRESULT
+}
+
+type IShellLinkWVtbl struct {
+	IUnknownVtbl
+	GetShowCmd uintptr
+}
+
+type IShellLinkW struct {
+	IUnknown
+}
+
+func (this *IShellLinkW) Vtbl() *IShellLinkWVtbl {
+	return (*IShellLinkWVtbl)(unsafe.Pointer(this.IUnknown.LpVtbl))
+}
+
+func (this *IShellLinkW) GetShowCmd(piShowCmd *int32) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetShowCmd, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(piShowCmd)))
+	return HRESULT(ret)
+}
+
+// IShellLinkWServerMethods appends the methods of IShellLinkW implemented by the go value of a ComServer to a vtable
+func IShellLinkWServerMethods(vtbl []uintptr) []uintptr {
+	vtbl = IUnknownServerMethods(vtbl)
+	return append(vtbl,
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *int32) uintptr {
+			impl := ComServerOf(this).Impl().(IShellLinkWInterface)
+			ret := impl.GetShowCmd(a0)
+			return uintptr(ret)
+		}),
+	)
+}
+
+var (
+	iShellLinkWServerOnce sync.Once
+	iShellLinkWServerVtbl []uintptr
+)
+
+// NewIShellLinkWServer returns a COM object implementing IShellLinkW by calling impl,
+// with a reference count of 1. impl usually embeds ComServerUnknown.
+func NewIShellLinkWServer(impl IShellLinkWInterface) *IShellLinkW {
+	iShellLinkWServerOnce.Do(func() {
+		iShellLinkWServerVtbl = IShellLinkWServerMethods(nil)
+	})
+	server := NewComServer(iShellLinkWServerVtbl, impl, &IID_IShellLinkW)
+	return (*IShellLinkW)(unsafe.Pointer(server))
+}
+
+// com classes
+
+// 00021401-0000-0000-c000-000000000046
+var CLSID_ShellLink = syscall.GUID{Data1: 0x00021401, Data2: 0x0000, Data3: 0x0000,
+	Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+
+// CoCreateShellLink creates an object of the class ShellLink and returns its IShellLinkW interface
+func CoCreateShellLink(clsCtx CLSCTX) (*IShellLinkW, HRESULT) {
+	var p *IShellLinkW
+	hr := CoCreateInstance(&CLSID_ShellLink, nil, clsCtx, &IID_IShellLinkW, unsafe.Pointer(&p))
+	return p, hr
+}
+
+// 3ad05575-8857-4850-9277-11b85bdb8e09
+var CLSID_FileOperation = syscall.GUID{Data1: 0x3ad05575, Data2: 0x8857, Data3: 0x4850,
+	Data4: [8]byte{0x92, 0x77, 0x11, 0xb8, 0x5b, 0xdb, 0x8e, 0x09}}
+
+// CoCreateFileOperation creates an object of the class FileOperation and returns its IUnknown interface
+func CoCreateFileOperation(clsCtx CLSCTX) (*IUnknown, HRESULT) {
+	var p *IUnknown
+	hr := CoCreateInstance(&CLSID_FileOperation, nil, clsCtx, &IID_IUnknown, unsafe.Pointer(&p))
+	return p, hr
+}
@@ -0,0 +1,138 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+type BOOL = int32
+type HANDLE = uintptr
+type HRESULT int32
+
+const (
+	S_OK    HRESULT = 0
+	S_FALSE HRESULT = 1
+	E_FAIL  HRESULT = -2147467259
+)
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	ERROR_SUCCESS        WIN32_ERROR = 0
+	ERROR_FILE_NOT_FOUND WIN32_ERROR = 2
+	ERROR_INVALID_HANDLE WIN32_ERROR = 6
+	ERROR_ALREADY_EXISTS WIN32_ERROR = 183
+)
+
+var win32ErrorNames = map[WIN32_ERROR]string{
+	0:   "ERROR_SUCCESS",
+	2:   "ERROR_FILE_NOT_FOUND",
+	6:   "ERROR_INVALID_HANDLE",
+	183: "ERROR_ALREADY_EXISTS",
+}
+
+// Error returns the constant name and the system message of the error code
+func (this WIN32_ERROR) Error() string {
+	msg := syscall.Errno(this).Error()
+	if name, ok := win32ErrorNames[this]; ok {
+		return name + ": " + msg
+	}
+	return msg
+}
+
+// Is reports whether target is the same error code,
+// as a WIN32_ERROR, a syscall.Errno or an HRESULT of FACILITY_WIN32
+func (this WIN32_ERROR) Is(target error) bool {
+	switch t := target.(type) {
+	case WIN32_ERROR:
+		return this == t
+	case syscall.Errno:
+		return uint32(this) == uint32(t)
+	case HRESULT:
+		return this != 0 && HRESULT_FROM_WIN32(this) == t
+	}
+	return false
+}
+
+// HRESULT_FROM_WIN32 returns the HRESULT of FACILITY_WIN32 wrapping code,
+// S_OK for ERROR_SUCCESS
+func HRESULT_FROM_WIN32(code WIN32_ERROR) HRESULT {
+	if int32(code) <= 0 {
+		return HRESULT(code)
+	}
+	return HRESULT(uint32(code)&0xFFFF | 7<<16 | 0x80000000)
+}
+
+var hresultNames = map[HRESULT]string{
+	0:           "S_OK",
+	1:           "S_FALSE",
+	-2147467259: "E_FAIL",
+}
+
+// Error returns the constant name and the hexadecimal value of the HRESULT
+func (this HRESULT) Error() string {
+	code := "0x" + strconv.FormatUint(uint64(uint32(this)), 16)
+	if name, ok := hresultNames[this]; ok {
+		return name + " (" + code + ")"
+	}
+	if uint32(this)&0xFFFF0000 == 0x80070000 {
+		return WIN32_ERROR(uint32(this)&0xFFFF).Error() + " (" + code + ")"
+	}
+	return "HRESULT " + code
+}
+
+// Is reports whether target is the same HRESULT,
+// or the WIN32_ERROR or syscall.Errno wrapped by an HRESULT of FACILITY_WIN32
+func (this HRESULT) Is(target error) bool {
+	switch t := target.(type) {
+	case HRESULT:
+		return this == t
+	case WIN32_ERROR:
+		return t != 0 && HRESULT_FROM_WIN32(t) == this
+	case syscall.Errno:
+		return t != 0 && HRESULT_FROM_WIN32(WIN32_ERROR(t)) == this
+	}
+	return false
+}
+
+var (
+	pCloseHandle uintptr
+	pCreateFileW uintptr
+	pGetFileSize uintptr
+)
+
+func CloseHandle(hObject HANDLE) (BOOL, error) {
+	addr := lazyAddr(&pCloseHandle, libKernel32, "CloseHandle")
+	ret, _, err := syscall.SyscallN(addr, hObject)
+	if ret == 0 {
+		if err == 0 {
+			err = 31 //ERROR_GEN_FAILURE
+		}
+		return BOOL(ret), WIN32_ERROR(err)
+	}
+	return BOOL(ret), nil
+}
+
+func CreateFileW(lpFileName *uint16, dwDesiredAccess uint32, dwCreationDisposition uint32) (HANDLE, error) {
+	addr := lazyAddr(&pCreateFileW, libKernel32, "CreateFileW")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpFileName)), uintptr(dwDesiredAccess), uintptr(dwCreationDisposition))
+	if ret == 0 || ret == ^uintptr(0) {
+		if err == 0 {
+			err = 31 //ERROR_GEN_FAILURE
+		}
+		return HANDLE(ret), WIN32_ERROR(err)
+	}
+	return HANDLE(ret), nil
+}
+
+func GetFileSize(hFile HANDLE, lpFileSizeHigh *uint32) (uint32, uint32) {
+	addr := lazyAddr(&pGetFileSize, libKernel32, "GetFileSize")
+	ret, _, err := syscall.SyscallN(addr, hFile, uintptr(unsafe.Pointer(lpFileSizeHigh)))
+	return uint32(ret), uint32(err)
+}
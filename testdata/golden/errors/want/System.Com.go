@@ -0,0 +1,311 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// enums
+
+// enum CLSCTX
+// flags
+type CLSCTX uint32
+
+const (
+	CLSCTX_INPROC_SERVER CLSCTX = 1
+	CLSCTX_LOCAL_SERVER  CLSCTX = 4
+)
+
+// coms
+
+// 00000000-0000-0000-c000-000000000046
+var IID_IUnknown = syscall.GUID{Data1: 0x00000000, Data2: 0x0000, Data3: 0x0000,
+	Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+
+type IUnknownInterface interface {
+	QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) error
+	AddRef() uint32
+	Release() uint32
+}
+
+type IUnknownVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+}
+
+type IUnknown struct {
+	LpVtbl *[1024]uintptr
+}
+
+func (this *IUnknown) Vtbl() *IUnknownVtbl {
+	return (*IUnknownVtbl)(unsafe.Pointer(this.LpVtbl))
+}
+
+func (this *IUnknown) QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) error {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().QueryInterface, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(riid)), uintptr(unsafe.Pointer(ppvObject)))
+	if int32(ret) < 0 {
+		return HRESULT(ret)
+	}
+	return nil
+}
+
+func (this *IUnknown) AddRef() uint32 {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().AddRef, uintptr(unsafe.Pointer(this)))
+	return uint32(ret)
+}
+
+func (this *IUnknown) Release() uint32 {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().Release, uintptr(unsafe.Pointer(this)))
+	return uint32(ret)
+}
+
+// ComServer is a COM object implemented by a go value,
+// it is created by the NewXxxServer functions generated for each interface.
+// The object is kept alive until its reference count drops to zero.
+type ComServer struct {
+	LpVtbl *[1024]uintptr
+	refs   int32
+	impl   interface{}
+	iids   []*syscall.GUID
+}
+
+var (
+	comServerMu sync.Mutex
+	comServers  = make(map[*ComServer]bool)
+)
+
+// NewComServer creates a COM object with the vtable vtbl dispatching to impl,
+// answering QueryInterface for IUnknown and iids. Its reference count starts at 1.
+func NewComServer(vtbl []uintptr, impl interface{}, iids ...*syscall.GUID) *ComServer {
+	server := &ComServer{
+		LpVtbl: (*[1024]uintptr)(unsafe.Pointer(&vtbl[0])),
+		refs:   1,
+		impl:   impl,
+		iids:   iids,
+	}
+	comServerMu.Lock()
+	comServers[server] = true
+	comServerMu.Unlock()
+	return server
+}
+
+// ComServerOf returns the server object of a COM this pointer
+func ComServerOf(this unsafe.Pointer) *ComServer {
+	return (*ComServer)(this)
+}
+
+// Impl returns the go value implementing the object
+func (this *ComServer) Impl() interface{} {
+	return this.impl
+}
+
+func (this *ComServer) AddRef() uint32 {
+	return uint32(atomic.AddInt32(&this.refs, 1))
+}
+
+func (this *ComServer) Release() uint32 {
+	refs := atomic.AddInt32(&this.refs, -1)
+	if refs == 0 {
+		comServerMu.Lock()
+		delete(comServers, this)
+		comServerMu.Unlock()
+	}
+	return uint32(refs)
+}
+
+func (this *ComServer) queryInterface(riid *syscall.GUID, ppvObject *uintptr) uintptr {
+	if *riid == IID_IUnknown {
+		*ppvObject = uintptr(unsafe.Pointer(this))
+		this.AddRef()
+		return 0
+	}
+	for _, iid := range this.iids {
+		if *riid == *iid {
+			*ppvObject = uintptr(unsafe.Pointer(this))
+			this.AddRef()
+			return 0
+		}
+	}
+	*ppvObject = 0
+	return 0x80004002 //E_NOINTERFACE
+}
+
+var (
+	iUnknownServerOnce   sync.Once
+	iUnknownServerThunks []uintptr
+)
+
+// IUnknownServerMethods appends the IUnknown methods of ComServer to a vtable
+func IUnknownServerMethods(vtbl []uintptr) []uintptr {
+	iUnknownServerOnce.Do(func() {
+		iUnknownServerThunks = []uintptr{
+			syscall.NewCallback(func(this unsafe.Pointer, riid *syscall.GUID, ppvObject *uintptr) uintptr {
+				return ComServerOf(this).queryInterface(riid, ppvObject)
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).AddRef())
+			}),
+			syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+				return uintptr(ComServerOf(this).Release())
+			}),
+		}
+	})
+	return append(vtbl, iUnknownServerThunks...)
+}
+
+// ComServerUnknown is embedded in go implementations of COM interfaces
+// to satisfy IUnknownInterface, a server object answers IUnknown calls itself.
+type ComServerUnknown struct{}
+
+func (ComServerUnknown) QueryInterface(riid *syscall.GUID, ppvObject unsafe.Pointer) error {
+	var ret error
+	return ret
+}
+
+func (ComServerUnknown) AddRef() uint32 {
+	var ret uint32
+	return ret
+}
+
+func (ComServerUnknown) Release() uint32 {
+	var ret uint32
+	return ret
+}
+
+// 6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6001
+var IID_IWidget = syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,
+	Data4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x01}}
+
+type IWidgetInterface interface {
+	IUnknownInterface
+	Refresh() error
+	GetCount(count *uint32) error
+}
+
+type IWidgetVtbl struct {
+	IUnknownVtbl
+	Refresh  uintptr
+	GetCount uintptr
+}
+
+type IWidget struct {
+	IUnknown
+}
+
+func (this *IWidget) Vtbl() *IWidgetVtbl {
+	return (*IWidgetVtbl)(unsafe.Pointer(this.IUnknown.LpVtbl))
+}
+
+func (this *IWidget) Refresh() error {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().Refresh, uintptr(unsafe.Pointer(this)))
+	if int32(ret) < 0 {
+		return HRESULT(ret)
+	}
+	return nil
+}
+
+func (this *IWidget) GetCount(count *uint32) error {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetCount, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(count)))
+	if int32(ret) < 0 {
+		return HRESULT(ret)
+	}
+	return nil
+}
+
+// IWidgetServerMethods appends the methods of IWidget implemented by the go value of a ComServer to a vtable
+func IWidgetServerMethods(vtbl []uintptr) []uintptr {
+	vtbl = IUnknownServerMethods(vtbl)
+	return append(vtbl,
+		syscall.NewCallback(func(this unsafe.Pointer) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			err := impl.Refresh()
+			if err == nil {
+				return 0
+			}
+			var hr HRESULT
+			if errors.As(err, &hr) {
+				return uintptr(hr)
+			}
+			var code WIN32_ERROR
+			if errors.As(err, &code) {
+				return uintptr(HRESULT_FROM_WIN32(code))
+			}
+			return 0x80004005 //E_FAIL
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *uint32) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			err := impl.GetCount(a0)
+			if err == nil {
+				return 0
+			}
+			var hr HRESULT
+			if errors.As(err, &hr) {
+				return uintptr(hr)
+			}
+			var code WIN32_ERROR
+			if errors.As(err, &code) {
+				return uintptr(HRESULT_FROM_WIN32(code))
+			}
+			return 0x80004005 //E_FAIL
+		}),
+	)
+}
+
+var (
+	iWidgetServerOnce sync.Once
+	iWidgetServerVtbl []uintptr
+)
+
+// NewIWidgetServer returns a COM object implementing IWidget by calling impl,
+// with a reference count of 1. impl usually embeds ComServerUnknown.
+func NewIWidgetServer(impl IWidgetInterface) *IWidget {
+	iWidgetServerOnce.Do(func() {
+		iWidgetServerVtbl = IWidgetServerMethods(nil)
+	})
+	server := NewComServer(iWidgetServerVtbl, impl, &IID_IWidget)
+	return (*IWidget)(unsafe.Pointer(server))
+}
+
+// com classes
+
+// 6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6002
+var CLSID_Widget = syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,
+	Data4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x02}}
+
+// CoCreateWidget creates an object of the class Widget and returns its IWidget interface
+func CoCreateWidget(clsCtx CLSCTX) (*IWidget, error) {
+	var p *IWidget
+	if err := CoCreateInstance(&CLSID_Widget, nil, clsCtx, &IID_IWidget, unsafe.Pointer(&p)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var (
+	pCoInitializeEx   uintptr
+	pCoCreateInstance uintptr
+)
+
+func CoInitializeEx(pvReserved unsafe.Pointer, dwCoInit uint32) error {
+	addr := lazyAddr(&pCoInitializeEx, libOle32, "CoInitializeEx")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(pvReserved), uintptr(dwCoInit))
+	if int32(ret) < 0 {
+		return HRESULT(ret)
+	}
+	return nil
+}
+
+func CoCreateInstance(rclsid *syscall.GUID, pUnkOuter *IUnknown, dwClsContext CLSCTX, riid *syscall.GUID, ppv unsafe.Pointer) error {
+	addr := lazyAddr(&pCoCreateInstance, libOle32, "CoCreateInstance")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(unsafe.Pointer(rclsid)), uintptr(unsafe.Pointer(pUnkOuter)), uintptr(dwClsContext), uintptr(unsafe.Pointer(riid)), uintptr(ppv))
+	if int32(ret) < 0 {
+		return HRESULT(ret)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"syscall"
+)
+
+// structs
+
+type HOOKS struct {
+	OnEnum     uintptr
+	OnProgress uintptr
+}
+
+// func types
+
+type ENUMPROC func(handle uintptr, lParam uintptr) int32
+
+var callbacksOfENUMPROC = &callbackSlots{newThunk: func(slots *callbackSlots, slot int) uintptr {
+	return syscall.NewCallback(func(a0 uintptr, a1 uintptr) uintptr {
+		fn := slots.get(slot).(ENUMPROC)
+		ret := fn(a0, a1)
+		return uintptr(ret)
+	})
+}}
+
+// NewENUMPROC returns a callback pointer calling fn.
+// Pass it to FreeCallback when it is no longer called, so that it can be reused.
+func NewENUMPROC(fn ENUMPROC) uintptr {
+	return callbacksOfENUMPROC.alloc(fn)
+}
+
+type PROGRESS func(percent float64)
+
+// PROGRESS has arguments syscall.NewCallback cannot pass
+
+var (
+	pEnumThings uintptr
+)
+
+func EnumThings(callback uintptr, lParam uintptr) int32 {
+	addr := lazyAddr(&pEnumThings, libUser32, "EnumThings")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(callback), uintptr(lParam))
+	return int32(ret)
+}
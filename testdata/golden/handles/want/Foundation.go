@@ -0,0 +1,116 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"runtime"
+	"syscall"
+)
+
+type BOOL = int32
+
+type HANDLE uintptr
+
+// Close closes the handle with CloseHandle, which returns the results of the call as they are
+func (this HANDLE) Close() error {
+	if ret, lastErr := CloseHandle(this); ret == 0 {
+		if lastErr == 0 {
+			return syscall.Errno(31) //ERROR_GEN_FAILURE
+		}
+		return syscall.Errno(lastErr)
+	}
+	return nil
+}
+
+// OwnedHANDLE owns a HANDLE, see HANDLE.Owned
+type OwnedHANDLE struct {
+	HANDLE
+}
+
+// Owned returns an owner of the handle that reports it to HandleLeaked
+// if it is garbage collected before Close is called.
+func (this HANDLE) Owned() *OwnedHANDLE {
+	owner := &OwnedHANDLE{this}
+	runtime.SetFinalizer(owner, func(owner *OwnedHANDLE) {
+		HandleLeaked("HANDLE", uintptr(owner.HANDLE))
+	})
+	return owner
+}
+
+// Close closes the handle, it is no longer reported as leaked
+func (this *OwnedHANDLE) Close() error {
+	runtime.SetFinalizer(this, nil)
+	return this.HANDLE.Close()
+}
+
+type HGDIOBJ uintptr
+
+type HBITMAP uintptr
+
+// HGDIOBJ returns the handle as a HGDIOBJ
+func (this HBITMAP) HGDIOBJ() HGDIOBJ {
+	return HGDIOBJ(this)
+}
+
+// Close closes the handle with DeleteObject, which returns the results of the call as they are
+func (this HBITMAP) Close() error {
+	if ret := DeleteObject(HGDIOBJ(this)); ret == 0 {
+		return syscall.Errno(31) //ERROR_GEN_FAILURE
+	}
+	return nil
+}
+
+// OwnedHBITMAP owns a HBITMAP, see HBITMAP.Owned
+type OwnedHBITMAP struct {
+	HBITMAP
+}
+
+// Owned returns an owner of the handle that reports it to HandleLeaked
+// if it is garbage collected before Close is called.
+func (this HBITMAP) Owned() *OwnedHBITMAP {
+	owner := &OwnedHBITMAP{this}
+	runtime.SetFinalizer(owner, func(owner *OwnedHBITMAP) {
+		HandleLeaked("HBITMAP", uintptr(owner.HBITMAP))
+	})
+	return owner
+}
+
+// Close closes the handle, it is no longer reported as leaked
+func (this *OwnedHBITMAP) Close() error {
+	runtime.SetFinalizer(this, nil)
+	return this.HBITMAP.Close()
+}
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	ERROR_SUCCESS        WIN32_ERROR = 0
+	ERROR_INVALID_HANDLE WIN32_ERROR = 6
+)
+
+var (
+	pCloseHandle         uintptr
+	pDeleteObject        uintptr
+	pWaitForSingleObject uintptr
+)
+
+func CloseHandle(hObject HANDLE) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pCloseHandle, libKernel32, "CloseHandle")
+	ret, _, err := syscall.SyscallN(addr, uintptr(hObject))
+	return BOOL(ret), WIN32_ERROR(err)
+}
+
+func DeleteObject(ho HGDIOBJ) BOOL {
+	addr := lazyAddr(&pDeleteObject, libGdi32, "DeleteObject")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(ho))
+	return BOOL(ret)
+}
+
+func WaitForSingleObject(hHandle HANDLE, dwMilliseconds uint32) (uint32, WIN32_ERROR) {
+	addr := lazyAddr(&pWaitForSingleObject, libKernel32, "WaitForSingleObject")
+	ret, _, err := syscall.SyscallN(addr, uintptr(hHandle), uintptr(dwMilliseconds))
+	return uint32(ret), WIN32_ERROR(err)
+}
@@ -0,0 +1,184 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+type BOOL = int32
+type HRESULT int32
+
+type HANDLE uintptr
+
+// Close closes the handle with CloseHandle, which returns the results of the call as they are
+func (this HANDLE) Close() error {
+	_, err := CloseHandle(this)
+	return err
+}
+
+// OwnedHANDLE owns a HANDLE, see HANDLE.Owned
+type OwnedHANDLE struct {
+	HANDLE
+}
+
+// Owned returns an owner of the handle that reports it to HandleLeaked
+// if it is garbage collected before Close is called.
+func (this HANDLE) Owned() *OwnedHANDLE {
+	owner := &OwnedHANDLE{this}
+	runtime.SetFinalizer(owner, func(owner *OwnedHANDLE) {
+		HandleLeaked("HANDLE", uintptr(owner.HANDLE))
+	})
+	return owner
+}
+
+// Close closes the handle, it is no longer reported as leaked
+func (this *OwnedHANDLE) Close() error {
+	runtime.SetFinalizer(this, nil)
+	return this.HANDLE.Close()
+}
+
+type HGDIOBJ uintptr
+
+type HBITMAP uintptr
+
+// HGDIOBJ returns the handle as a HGDIOBJ
+func (this HBITMAP) HGDIOBJ() HGDIOBJ {
+	return HGDIOBJ(this)
+}
+
+// Close closes the handle with DeleteObject, which returns the results of the call as they are
+func (this HBITMAP) Close() error {
+	if ret := DeleteObject(HGDIOBJ(this)); ret == 0 {
+		return WIN32_ERROR(31) //ERROR_GEN_FAILURE
+	}
+	return nil
+}
+
+// OwnedHBITMAP owns a HBITMAP, see HBITMAP.Owned
+type OwnedHBITMAP struct {
+	HBITMAP
+}
+
+// Owned returns an owner of the handle that reports it to HandleLeaked
+// if it is garbage collected before Close is called.
+func (this HBITMAP) Owned() *OwnedHBITMAP {
+	owner := &OwnedHBITMAP{this}
+	runtime.SetFinalizer(owner, func(owner *OwnedHBITMAP) {
+		HandleLeaked("HBITMAP", uintptr(owner.HBITMAP))
+	})
+	return owner
+}
+
+// Close closes the handle, it is no longer reported as leaked
+func (this *OwnedHBITMAP) Close() error {
+	runtime.SetFinalizer(this, nil)
+	return this.HBITMAP.Close()
+}
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	ERROR_SUCCESS        WIN32_ERROR = 0
+	ERROR_INVALID_HANDLE WIN32_ERROR = 6
+)
+
+var win32ErrorNames = map[WIN32_ERROR]string{
+	0: "ERROR_SUCCESS",
+	6: "ERROR_INVALID_HANDLE",
+}
+
+// Error returns the constant name and the system message of the error code
+func (this WIN32_ERROR) Error() string {
+	msg := syscall.Errno(this).Error()
+	if name, ok := win32ErrorNames[this]; ok {
+		return name + ": " + msg
+	}
+	return msg
+}
+
+// Is reports whether target is the same error code,
+// as a WIN32_ERROR, a syscall.Errno or an HRESULT of FACILITY_WIN32
+func (this WIN32_ERROR) Is(target error) bool {
+	switch t := target.(type) {
+	case WIN32_ERROR:
+		return this == t
+	case syscall.Errno:
+		return uint32(this) == uint32(t)
+	case HRESULT:
+		return this != 0 && HRESULT_FROM_WIN32(this) == t
+	}
+	return false
+}
+
+// HRESULT_FROM_WIN32 returns the HRESULT of FACILITY_WIN32 wrapping code,
+// S_OK for ERROR_SUCCESS
+func HRESULT_FROM_WIN32(code WIN32_ERROR) HRESULT {
+	if int32(code) <= 0 {
+		return HRESULT(code)
+	}
+	return HRESULT(uint32(code)&0xFFFF | 7<<16 | 0x80000000)
+}
+
+var hresultNames = map[HRESULT]string{}
+
+// Error returns the constant name and the hexadecimal value of the HRESULT
+func (this HRESULT) Error() string {
+	code := "0x" + strconv.FormatUint(uint64(uint32(this)), 16)
+	if name, ok := hresultNames[this]; ok {
+		return name + " (" + code + ")"
+	}
+	if uint32(this)&0xFFFF0000 == 0x80070000 {
+		return WIN32_ERROR(uint32(this)&0xFFFF).Error() + " (" + code + ")"
+	}
+	return "HRESULT " + code
+}
+
+// Is reports whether target is the same HRESULT,
+// or the WIN32_ERROR or syscall.Errno wrapped by an HRESULT of FACILITY_WIN32
+func (this HRESULT) Is(target error) bool {
+	switch t := target.(type) {
+	case HRESULT:
+		return this == t
+	case WIN32_ERROR:
+		return t != 0 && HRESULT_FROM_WIN32(t) == this
+	case syscall.Errno:
+		return t != 0 && HRESULT_FROM_WIN32(WIN32_ERROR(t)) == this
+	}
+	return false
+}
+
+var (
+	pCloseHandle         uintptr
+	pDeleteObject        uintptr
+	pWaitForSingleObject uintptr
+)
+
+func CloseHandle(hObject HANDLE) (BOOL, error) {
+	addr := lazyAddr(&pCloseHandle, libKernel32, "CloseHandle")
+	ret, _, err := syscall.SyscallN(addr, uintptr(hObject))
+	if ret == 0 {
+		if err == 0 {
+			err = 31 //ERROR_GEN_FAILURE
+		}
+		return BOOL(ret), WIN32_ERROR(err)
+	}
+	return BOOL(ret), nil
+}
+
+func DeleteObject(ho HGDIOBJ) BOOL {
+	addr := lazyAddr(&pDeleteObject, libGdi32, "DeleteObject")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(ho))
+	return BOOL(ret)
+}
+
+func WaitForSingleObject(hHandle HANDLE, dwMilliseconds uint32) (uint32, uint32) {
+	addr := lazyAddr(&pWaitForSingleObject, libKernel32, "WaitForSingleObject")
+	ret, _, err := syscall.SyscallN(addr, uintptr(hHandle), uintptr(dwMilliseconds))
+	return uint32(ret), uint32(err)
+}
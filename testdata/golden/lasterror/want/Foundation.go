@@ -0,0 +1,203 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+type PWSTR = *uint16
+type BOOL = int32
+type HRESULT int32
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	ERROR_SUCCESS             WIN32_ERROR = 0
+	ERROR_INSUFFICIENT_BUFFER WIN32_ERROR = 122
+	ERROR_MORE_DATA           WIN32_ERROR = 234
+)
+
+var win32ErrorNames = map[WIN32_ERROR]string{
+	0:   "ERROR_SUCCESS",
+	122: "ERROR_INSUFFICIENT_BUFFER",
+	234: "ERROR_MORE_DATA",
+}
+
+// Error returns the constant name and the system message of the error code
+func (this WIN32_ERROR) Error() string {
+	msg := syscall.Errno(this).Error()
+	if name, ok := win32ErrorNames[this]; ok {
+		return name + ": " + msg
+	}
+	return msg
+}
+
+// Is reports whether target is the same error code,
+// as a WIN32_ERROR, a syscall.Errno or an HRESULT of FACILITY_WIN32
+func (this WIN32_ERROR) Is(target error) bool {
+	switch t := target.(type) {
+	case WIN32_ERROR:
+		return this == t
+	case syscall.Errno:
+		return uint32(this) == uint32(t)
+	case HRESULT:
+		return this != 0 && HRESULT_FROM_WIN32(this) == t
+	}
+	return false
+}
+
+// HRESULT_FROM_WIN32 returns the HRESULT of FACILITY_WIN32 wrapping code,
+// S_OK for ERROR_SUCCESS
+func HRESULT_FROM_WIN32(code WIN32_ERROR) HRESULT {
+	if int32(code) <= 0 {
+		return HRESULT(code)
+	}
+	return HRESULT(uint32(code)&0xFFFF | 7<<16 | 0x80000000)
+}
+
+var hresultNames = map[HRESULT]string{}
+
+// Error returns the constant name and the hexadecimal value of the HRESULT
+func (this HRESULT) Error() string {
+	code := "0x" + strconv.FormatUint(uint64(uint32(this)), 16)
+	if name, ok := hresultNames[this]; ok {
+		return name + " (" + code + ")"
+	}
+	if uint32(this)&0xFFFF0000 == 0x80070000 {
+		return WIN32_ERROR(uint32(this)&0xFFFF).Error() + " (" + code + ")"
+	}
+	return "HRESULT " + code
+}
+
+// Is reports whether target is the same HRESULT,
+// or the WIN32_ERROR or syscall.Errno wrapped by an HRESULT of FACILITY_WIN32
+func (this HRESULT) Is(target error) bool {
+	switch t := target.(type) {
+	case HRESULT:
+		return this == t
+	case WIN32_ERROR:
+		return t != 0 && HRESULT_FROM_WIN32(t) == this
+	case syscall.Errno:
+		return t != 0 && HRESULT_FROM_WIN32(WIN32_ERROR(t)) == this
+	}
+	return false
+}
+
+// structs
+
+type POINT struct {
+	X int32
+	Y int32
+}
+
+var (
+	pGetCursorPos       uintptr
+	pGetUserNameW       uintptr
+	pGetModuleFileNameW uintptr
+)
+
+func GetCursorPos(lpPoint *POINT) (BOOL, error) {
+	addr := lazyAddr(&pGetCursorPos, libUser32, "GetCursorPos")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpPoint)))
+	if ret == 0 {
+		if err == 0 {
+			err = 31 //ERROR_GEN_FAILURE
+		}
+		return BOOL(ret), WIN32_ERROR(err)
+	}
+	return BOOL(ret), nil
+}
+
+// GetCursorPosGo calls GetCursorPos returning lpPoint.
+func GetCursorPosGo() (lpPoint POINT, ret BOOL, err error) {
+	ret, err = GetCursorPos(&lpPoint)
+	return
+}
+
+func GetUserNameW(lpBuffer *uint16, pcbBuffer *uint32) (BOOL, error) {
+	addr := lazyAddr(&pGetUserNameW, libAdvapi32, "GetUserNameW")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpBuffer)), uintptr(unsafe.Pointer(pcbBuffer)))
+	if ret == 0 {
+		if err == 0 {
+			err = 31 //ERROR_GEN_FAILURE
+		}
+		return BOOL(ret), WIN32_ERROR(err)
+	}
+	return BOOL(ret), nil
+}
+
+// GetUserNameWGo calls GetUserNameW taking slices for lpBuffer, passing the length of lpBuffer as
+// pcbBuffer, returning pcbBuffer.
+// lpBuffer is optional and may be nil.
+func GetUserNameWGo(lpBuffer []uint16) (pcbBuffer uint32, ret BOOL, err error) {
+	var lpBufferPtr *uint16
+	if len(lpBuffer) > 0 {
+		lpBufferPtr = &lpBuffer[0]
+	}
+	pcbBuffer = uint32(len(lpBuffer))
+	ret, err = GetUserNameW(lpBufferPtr, &pcbBuffer)
+	return
+}
+
+// GetUserNameWAlloc calls GetUserNameW returning lpBuffer in a buffer grown while the call fails
+// with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA.
+func GetUserNameWAlloc() (lpBuffer string, ret BOOL, err error) {
+	lpBufferBuf := make([]uint16, 256)
+	var pcbBuffer uint32
+	for {
+		pcbBuffer = uint32(len(lpBufferBuf))
+		ret, err = GetUserNameW(&lpBufferBuf[0], &pcbBuffer)
+		if ret != 0 || (err != ERROR_INSUFFICIENT_BUFFER && err != ERROR_MORE_DATA) {
+			break
+		}
+		n := 2 * len(lpBufferBuf)
+		if int(pcbBuffer) > len(lpBufferBuf) {
+			n = int(pcbBuffer)
+		}
+		lpBufferBuf = make([]uint16, n)
+	}
+	if int(pcbBuffer) < len(lpBufferBuf) {
+		lpBufferBuf = lpBufferBuf[:pcbBuffer]
+	}
+	lpBuffer = syscall.UTF16ToString(lpBufferBuf)
+	return
+}
+
+func GetModuleFileNameW(hModule uintptr, lpFilename *uint16, nSize uint32) (uint32, uint32) {
+	addr := lazyAddr(&pGetModuleFileNameW, libKernel32, "GetModuleFileNameW")
+	ret, _, err := syscall.SyscallN(addr, uintptr(hModule), uintptr(unsafe.Pointer(lpFilename)), uintptr(nSize))
+	return uint32(ret), uint32(err)
+}
+
+// GetModuleFileNameWGo calls GetModuleFileNameW taking slices for lpFilename, passing the length of
+// lpFilename as nSize.
+func GetModuleFileNameWGo(hModule uintptr, lpFilename []uint16) (ret uint32, lastErr uint32) {
+	var lpFilenamePtr *uint16
+	if len(lpFilename) > 0 {
+		lpFilenamePtr = &lpFilename[0]
+	}
+	ret, lastErr = GetModuleFileNameW(hModule, lpFilenamePtr, uint32(len(lpFilename)))
+	return
+}
+
+// GetModuleFileNameWAlloc calls GetModuleFileNameW returning lpFilename in a buffer grown while the
+// call fails with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA.
+func GetModuleFileNameWAlloc(hModule uintptr) (lpFilename string, ret uint32, lastErr uint32) {
+	lpFilenameBuf := make([]uint16, 256)
+	for {
+		ret, lastErr = GetModuleFileNameW(hModule, &lpFilenameBuf[0], uint32(len(lpFilenameBuf)))
+		if ret != 0 && int(ret) < len(lpFilenameBuf) || (lastErr != uint32(ERROR_INSUFFICIENT_BUFFER) && lastErr != uint32(ERROR_MORE_DATA)) {
+			break
+		}
+		n := 2 * len(lpFilenameBuf)
+		lpFilenameBuf = make([]uint16, n)
+	}
+	lpFilename = syscall.UTF16ToString(lpFilenameBuf)
+	return
+}
@@ -0,0 +1,33 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+//go:build amd64
+
+package win32
+
+import (
+	"unsafe"
+)
+
+// The size and alignment of the structs and the offsets of their fields
+// as computed by the generator. A differing layout fails to compile,
+// the constant index of its assertion is out of range.
+
+// COORD: size 4, align 2
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(COORD{})-4]
+	_ = [1]struct{}{}[unsafe.Alignof(COORD{})-2]
+	_ = [1]struct{}{}[unsafe.Offsetof(COORD{}.X)]
+	_ = [1]struct{}{}[unsafe.Offsetof(COORD{}.Y)-2]
+)
+
+// CONSOLE_FONT_INFOEX: size 84, align 4
+var (
+	_ = [1]struct{}{}[unsafe.Sizeof(CONSOLE_FONT_INFOEX{})-84]
+	_ = [1]struct{}{}[unsafe.Alignof(CONSOLE_FONT_INFOEX{})-4]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.CbSize)]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.NFont)-4]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.DwFontSize)-8]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.FontFamily)-12]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.FontWeight)-16]
+	_ = [1]struct{}{}[unsafe.Offsetof(CONSOLE_FONT_INFOEX{}.FaceName)-20]
+)
@@ -0,0 +1,62 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"unsafe"
+)
+
+// structs
+
+type VALUE struct {
+	Data [1]uint64
+}
+
+func (this *VALUE) I() *int64 {
+	return (*int64)(unsafe.Pointer(this))
+}
+
+func (this *VALUE) IVal() int64 {
+	return *(*int64)(unsafe.Pointer(this))
+}
+
+func (this *VALUE) D() *float64 {
+	return (*float64)(unsafe.Pointer(this))
+}
+
+func (this *VALUE) DVal() float64 {
+	return *(*float64)(unsafe.Pointer(this))
+}
+
+func (this *VALUE) P() *unsafe.Pointer {
+	return (*unsafe.Pointer)(unsafe.Pointer(this))
+}
+
+func (this *VALUE) PVal() unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(this))
+}
+
+type TAGGED_Anonymous_ struct {
+	Data [1]uint32
+}
+
+func (this *TAGGED_Anonymous_) AsLong() *int32 {
+	return (*int32)(unsafe.Pointer(this))
+}
+
+func (this *TAGGED_Anonymous_) AsLongVal() int32 {
+	return *(*int32)(unsafe.Pointer(this))
+}
+
+func (this *TAGGED_Anonymous_) AsBytes() *[4]uint8 {
+	return (*[4]uint8)(unsafe.Pointer(this))
+}
+
+func (this *TAGGED_Anonymous_) AsBytesVal() [4]uint8 {
+	return *(*[4]uint8)(unsafe.Pointer(this))
+}
+
+type TAGGED struct {
+	Tag uint16
+	TAGGED_Anonymous_
+}
@@ -0,0 +1,246 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+//go:build amd64 || 386
+
+package win32
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+type HWIDGET = uintptr
+
+const (
+	WS_DEFAULT WIDGET_STYLE = 1
+)
+
+var (
+	WIDGET_GUID_DEFAULT syscall.GUID = syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,
+		Data4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x03}}
+)
+
+// enums
+
+// enum WIDGET_STYLE
+// flags
+type WIDGET_STYLE uint32
+
+const (
+	WS_NONE   WIDGET_STYLE = 0
+	WS_BORDER WIDGET_STYLE = 1
+	WS_SHADOW WIDGET_STYLE = 2
+)
+
+// enum WIDGET_KIND
+type WIDGET_KIND uint8
+
+const (
+	Button WIDGET_KIND = 1
+	Label  WIDGET_KIND = 2
+)
+
+// structs
+
+type WIDGET_INFO_Anonymous__Anonymous_ struct {
+	Low  uint16
+	High uint16
+}
+
+type WIDGET_INFO_Anonymous_ struct {
+	Data [1]uint32
+}
+
+func (this *WIDGET_INFO_Anonymous_) Value() *uint32 {
+	return (*uint32)(unsafe.Pointer(this))
+}
+
+func (this *WIDGET_INFO_Anonymous_) ValueVal() uint32 {
+	return *(*uint32)(unsafe.Pointer(this))
+}
+
+func (this *WIDGET_INFO_Anonymous_) Parts() *WIDGET_INFO_Anonymous__Anonymous_ {
+	return (*WIDGET_INFO_Anonymous__Anonymous_)(unsafe.Pointer(this))
+}
+
+func (this *WIDGET_INFO_Anonymous_) PartsVal() WIDGET_INFO_Anonymous__Anonymous_ {
+	return *(*WIDGET_INFO_Anonymous__Anonymous_)(unsafe.Pointer(this))
+}
+
+type WIDGET_NAMEA struct {
+	First uint8
+}
+
+type WIDGET_NAME = WIDGET_NAMEW
+type WIDGET_NAMEW struct {
+	First uint16
+}
+
+type DEVPROPKEY struct {
+	Fmtid syscall.GUID
+	Pid   uint32
+}
+
+// func types
+
+type WIDGETENUMPROC func(hWidget HWIDGET, lParam uintptr) BOOL
+
+var callbacksOfWIDGETENUMPROC = &callbackSlots{newThunk: func(slots *callbackSlots, slot int) uintptr {
+	return syscall.NewCallback(func(a0 uintptr, a1 uintptr) uintptr {
+		fn := slots.get(slot).(WIDGETENUMPROC)
+		ret := fn(HWIDGET(a0), a1)
+		return uintptr(ret)
+	})
+}}
+
+// NewWIDGETENUMPROC returns a callback pointer calling fn.
+// Pass it to FreeCallback when it is no longer called, so that it can be reused.
+func NewWIDGETENUMPROC(fn WIDGETENUMPROC) uintptr {
+	return callbacksOfWIDGETENUMPROC.alloc(fn)
+}
+
+// coms
+
+// 6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6001
+var IID_IWidget = syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,
+	Data4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x01}}
+
+type IWidgetInterface interface {
+	IUnknownInterface
+	GetInfo(info *WIDGET_INFO) HRESULT
+	GetName(name *PWSTR) HRESULT
+	GetParent(parent **IWidget) HRESULT
+	SetStyles(styles *WIDGET_STYLE, count uint32) HRESULT
+}
+
+type IWidgetVtbl struct {
+	IUnknownVtbl
+	GetInfo   uintptr
+	GetName   uintptr
+	GetParent uintptr
+	SetStyles uintptr
+}
+
+type IWidget struct {
+	IUnknown
+}
+
+func (this *IWidget) Vtbl() *IWidgetVtbl {
+	return (*IWidgetVtbl)(unsafe.Pointer(this.IUnknown.LpVtbl))
+}
+
+func (this *IWidget) GetInfo(info *WIDGET_INFO) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetInfo, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(info)))
+	return HRESULT(ret)
+}
+
+func (this *IWidget) GetName(name *PWSTR) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetName, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(name)))
+	return HRESULT(ret)
+}
+
+func (this *IWidget) GetParent(parent **IWidget) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().GetParent, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(parent)))
+	return HRESULT(ret)
+}
+
+func (this *IWidget) SetStyles(styles *WIDGET_STYLE, count uint32) HRESULT {
+	ret, _, _ := syscall.SyscallN(this.Vtbl().SetStyles, uintptr(unsafe.Pointer(this)), uintptr(unsafe.Pointer(styles)), uintptr(count))
+	return HRESULT(ret)
+}
+
+// IWidgetServerMethods appends the methods of IWidget implemented by the go value of a ComServer to a vtable
+func IWidgetServerMethods(vtbl []uintptr) []uintptr {
+	vtbl = IUnknownServerMethods(vtbl)
+	return append(vtbl,
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *WIDGET_INFO) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			ret := impl.GetInfo(a0)
+			return uintptr(ret)
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *PWSTR) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			ret := impl.GetName(a0)
+			return uintptr(ret)
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 **IWidget) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			ret := impl.GetParent(a0)
+			return uintptr(ret)
+		}),
+		syscall.NewCallback(func(this unsafe.Pointer, a0 *WIDGET_STYLE, a1 uintptr) uintptr {
+			impl := ComServerOf(this).Impl().(IWidgetInterface)
+			ret := impl.SetStyles(a0, uint32(a1))
+			return uintptr(ret)
+		}),
+	)
+}
+
+var (
+	iWidgetServerOnce sync.Once
+	iWidgetServerVtbl []uintptr
+)
+
+// NewIWidgetServer returns a COM object implementing IWidget by calling impl,
+// with a reference count of 1. impl usually embeds ComServerUnknown.
+func NewIWidgetServer(impl IWidgetInterface) *IWidget {
+	iWidgetServerOnce.Do(func() {
+		iWidgetServerVtbl = IWidgetServerMethods(nil)
+	})
+	server := NewComServer(iWidgetServerVtbl, impl, &IID_IWidget)
+	return (*IWidget)(unsafe.Pointer(server))
+}
+
+// com classes
+
+// 6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6002
+var CLSID_Widget = syscall.GUID{Data1: 0x6b0d8e6e, Data2: 0x3c5a, Data3: 0x4b8f,
+	Data4: [8]byte{0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x02}}
+
+var (
+	pCreateWidgetW    uintptr
+	pCreateWidgetA    uintptr
+	pDestroyWidget    uintptr
+	pGetWidgetData    uintptr
+	pEnumWidgets      uintptr
+	pCoCreateInstance uintptr
+)
+
+var CreateWidget = CreateWidgetW
+
+func CreateWidgetW(name PWSTR, style WIDGET_STYLE, parent HWND) (HWIDGET, WIN32_ERROR) {
+	addr := lazyAddr(&pCreateWidgetW, libUser32, "CreateWidgetW")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(name)), uintptr(style), parent)
+	return HWIDGET(ret), WIN32_ERROR(err)
+}
+
+func CreateWidgetA(name *uint8, style WIDGET_STYLE, parent HWND) (HWIDGET, WIN32_ERROR) {
+	addr := lazyAddr(&pCreateWidgetA, libUser32, "CreateWidgetA")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(name)), uintptr(style), parent)
+	return HWIDGET(ret), WIN32_ERROR(err)
+}
+
+func DestroyWidget(hWidget HWIDGET) BOOL {
+	addr := lazyAddr(&pDestroyWidget, libUser32, "DestroyWidget")
+	ret, _, _ := syscall.SyscallN(addr, hWidget)
+	return BOOL(ret)
+}
+
+func GetWidgetData(hWidget HWIDGET, data unsafe.Pointer, size uint32, reserved unsafe.Pointer) (uint32, WIN32_ERROR) {
+	addr := lazyAddr(&pGetWidgetData, libUser32, "GetWidgetData")
+	ret, _, err := syscall.SyscallN(addr, hWidget, uintptr(data), uintptr(size), uintptr(reserved))
+	return uint32(ret), WIN32_ERROR(err)
+}
+
+func EnumWidgets(proc uintptr, lParam uintptr, ids *uint32) BOOL {
+	addr := lazyAddr(&pEnumWidgets, libUser32, "EnumWidgets")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(proc), uintptr(lParam), uintptr(unsafe.Pointer(ids)))
+	return BOOL(ret)
+}
+
+func CoCreateInstance(rclsid *syscall.GUID, riid *syscall.GUID, ppv unsafe.Pointer) HRESULT {
+	addr := lazyAddr(&pCoCreateInstance, libOle32, "CoCreateInstance")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(unsafe.Pointer(rclsid)), uintptr(unsafe.Pointer(riid)), uintptr(ppv))
+	return HRESULT(ret)
+}
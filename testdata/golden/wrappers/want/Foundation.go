@@ -0,0 +1,237 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type HANDLE = uintptr
+type PWSTR = *uint16
+type PSTR = *uint8
+type BOOL = int32
+type HWND = uintptr
+
+// enums
+
+// enum WIN32_ERROR
+type WIN32_ERROR uint32
+
+const (
+	ERROR_SUCCESS             WIN32_ERROR = 0
+	ERROR_INSUFFICIENT_BUFFER WIN32_ERROR = 122
+	ERROR_MORE_DATA           WIN32_ERROR = 234
+)
+
+// structs
+
+type POINT struct {
+	X int32
+	Y int32
+}
+
+var (
+	pMessageBoxW      uintptr
+	pSetWindowTextA   uintptr
+	pGetWindowTextW   uintptr
+	pRegQueryValueExW uintptr
+	pGetCursorPos     uintptr
+	pSetKeyboardState uintptr
+	pGetUserNameW     uintptr
+)
+
+var MessageBox = MessageBoxW
+var MessageBoxGo = MessageBoxWGo
+
+func MessageBoxW(hWnd HWND, lpText PWSTR, lpCaption PWSTR, uType uint32) (int32, WIN32_ERROR) {
+	addr := lazyAddr(&pMessageBoxW, libUser32, "MessageBoxW")
+	ret, _, err := syscall.SyscallN(addr, hWnd, uintptr(unsafe.Pointer(lpText)), uintptr(unsafe.Pointer(lpCaption)), uintptr(uType))
+	return int32(ret), WIN32_ERROR(err)
+}
+
+// MessageBoxWGo calls MessageBoxW taking go strings for lpText and lpCaption.
+// An empty lpText or lpCaption is passed as null.
+func MessageBoxWGo(hWnd HWND, lpText string, lpCaption string, uType uint32) (ret int32, lastErr WIN32_ERROR, err error) {
+	var lpTextPtr *uint16
+	if lpText != "" {
+		lpTextPtr, err = syscall.UTF16PtrFromString(lpText)
+		if err != nil {
+			return
+		}
+	}
+	var lpCaptionPtr *uint16
+	if lpCaption != "" {
+		lpCaptionPtr, err = syscall.UTF16PtrFromString(lpCaption)
+		if err != nil {
+			return
+		}
+	}
+	ret, lastErr = MessageBoxW(hWnd, lpTextPtr, lpCaptionPtr, uType)
+	return
+}
+
+func SetWindowTextA(hWnd HWND, lpString PSTR) BOOL {
+	addr := lazyAddr(&pSetWindowTextA, libUser32, "SetWindowTextA")
+	ret, _, _ := syscall.SyscallN(addr, hWnd, uintptr(unsafe.Pointer(lpString)))
+	return BOOL(ret)
+}
+
+// SetWindowTextAGo calls SetWindowTextA taking go strings for lpString.
+func SetWindowTextAGo(hWnd HWND, lpString string) (ret BOOL, err error) {
+	lpStringPtr, err := syscall.BytePtrFromString(lpString)
+	if err != nil {
+		return
+	}
+	ret = SetWindowTextA(hWnd, lpStringPtr)
+	return
+}
+
+func GetWindowTextW(hWnd HWND, lpString *uint16, nMaxCount int32) (int32, WIN32_ERROR) {
+	addr := lazyAddr(&pGetWindowTextW, libUser32, "GetWindowTextW")
+	ret, _, err := syscall.SyscallN(addr, hWnd, uintptr(unsafe.Pointer(lpString)), uintptr(nMaxCount))
+	return int32(ret), WIN32_ERROR(err)
+}
+
+// GetWindowTextWGo calls GetWindowTextW taking slices for lpString, passing the length of lpString
+// as nMaxCount.
+func GetWindowTextWGo(hWnd HWND, lpString []uint16) (ret int32, lastErr WIN32_ERROR) {
+	var lpStringPtr *uint16
+	if len(lpString) > 0 {
+		lpStringPtr = &lpString[0]
+	}
+	ret, lastErr = GetWindowTextW(hWnd, lpStringPtr, int32(len(lpString)))
+	return
+}
+
+func RegQueryValueExW(hKey uintptr, lpValueName PWSTR, lpReserved *uint32, lpType *uint32, lpData *uint8, lpcbData *uint32) WIN32_ERROR {
+	addr := lazyAddr(&pRegQueryValueExW, libAdvapi32, "RegQueryValueExW")
+	ret, _, _ := syscall.SyscallN(addr, uintptr(hKey), uintptr(unsafe.Pointer(lpValueName)), uintptr(unsafe.Pointer(lpReserved)), uintptr(unsafe.Pointer(lpType)), uintptr(unsafe.Pointer(lpData)), uintptr(unsafe.Pointer(lpcbData)))
+	return WIN32_ERROR(ret)
+}
+
+// RegQueryValueExWGo calls RegQueryValueExW taking go strings for lpValueName, taking slices for
+// lpData, passing the length of lpData as lpcbData, returning lpType and lpcbData, passing zero for
+// the reserved lpReserved.
+// An empty lpValueName is passed as null.
+// lpData is optional and may be nil.
+func RegQueryValueExWGo(hKey uintptr, lpValueName string, lpData []uint8) (lpType uint32, lpcbData uint32, ret WIN32_ERROR, err error) {
+	var lpValueNamePtr *uint16
+	if lpValueName != "" {
+		lpValueNamePtr, err = syscall.UTF16PtrFromString(lpValueName)
+		if err != nil {
+			return
+		}
+	}
+	var lpDataPtr *uint8
+	if len(lpData) > 0 {
+		lpDataPtr = &lpData[0]
+	}
+	lpcbData = uint32(len(lpData))
+	ret = RegQueryValueExW(hKey, lpValueNamePtr, nil, &lpType, lpDataPtr, &lpcbData)
+	return
+}
+
+// RegQueryValueExWAlloc calls RegQueryValueExW returning lpData in a buffer grown while the call
+// fails with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA, taking go strings for lpValueName,
+// returning lpType, passing zero for the reserved lpReserved.
+// An empty lpValueName is passed as null.
+func RegQueryValueExWAlloc(hKey uintptr, lpValueName string) (lpData []uint8, lpType uint32, ret WIN32_ERROR, err error) {
+	lpDataBuf := make([]uint8, 256)
+	var lpcbData uint32
+	var lpValueNamePtr *uint16
+	if lpValueName != "" {
+		lpValueNamePtr, err = syscall.UTF16PtrFromString(lpValueName)
+		if err != nil {
+			return
+		}
+	}
+	for {
+		lpcbData = uint32(len(lpDataBuf))
+		ret = RegQueryValueExW(hKey, lpValueNamePtr, nil, &lpType, &lpDataBuf[0], &lpcbData)
+		if ret != ERROR_INSUFFICIENT_BUFFER && ret != ERROR_MORE_DATA {
+			break
+		}
+		n := 2 * len(lpDataBuf)
+		if int(lpcbData) > len(lpDataBuf) {
+			n = int(lpcbData)
+		}
+		lpDataBuf = make([]uint8, n)
+	}
+	if int(lpcbData) < len(lpDataBuf) {
+		lpDataBuf = lpDataBuf[:lpcbData]
+	}
+	lpData = lpDataBuf
+	return
+}
+
+func GetCursorPos(lpPoint *POINT) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pGetCursorPos, libUser32, "GetCursorPos")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpPoint)))
+	return BOOL(ret), WIN32_ERROR(err)
+}
+
+// GetCursorPosGo calls GetCursorPos returning lpPoint.
+func GetCursorPosGo() (lpPoint POINT, ret BOOL, lastErr WIN32_ERROR) {
+	ret, lastErr = GetCursorPos(&lpPoint)
+	return
+}
+
+func SetKeyboardState(lpKeyState *uint8) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pSetKeyboardState, libUser32, "SetKeyboardState")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpKeyState)))
+	return BOOL(ret), WIN32_ERROR(err)
+}
+
+// SetKeyboardStateGo calls SetKeyboardState taking array pointers for lpKeyState.
+func SetKeyboardStateGo(lpKeyState *[256]uint8) (ret BOOL, lastErr WIN32_ERROR) {
+	var lpKeyStatePtr *uint8
+	if lpKeyState != nil {
+		lpKeyStatePtr = &lpKeyState[0]
+	}
+	ret, lastErr = SetKeyboardState(lpKeyStatePtr)
+	return
+}
+
+func GetUserNameW(lpBuffer *uint16, pcbBuffer *uint32) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pGetUserNameW, libAdvapi32, "GetUserNameW")
+	ret, _, err := syscall.SyscallN(addr, uintptr(unsafe.Pointer(lpBuffer)), uintptr(unsafe.Pointer(pcbBuffer)))
+	return BOOL(ret), WIN32_ERROR(err)
+}
+
+// GetUserNameWGo calls GetUserNameW taking slices for lpBuffer, passing the length of lpBuffer as
+// pcbBuffer, returning pcbBuffer.
+// lpBuffer is optional and may be nil.
+func GetUserNameWGo(lpBuffer []uint16) (pcbBuffer uint32, ret BOOL, lastErr WIN32_ERROR) {
+	var lpBufferPtr *uint16
+	if len(lpBuffer) > 0 {
+		lpBufferPtr = &lpBuffer[0]
+	}
+	pcbBuffer = uint32(len(lpBuffer))
+	ret, lastErr = GetUserNameW(lpBufferPtr, &pcbBuffer)
+	return
+}
+
+// GetUserNameWAlloc calls GetUserNameW returning lpBuffer in a buffer grown while the call fails
+// with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA.
+func GetUserNameWAlloc() (lpBuffer string, ret BOOL, lastErr WIN32_ERROR) {
+	lpBufferBuf := make([]uint16, 256)
+	var pcbBuffer uint32
+	for {
+		pcbBuffer = uint32(len(lpBufferBuf))
+		ret, lastErr = GetUserNameW(&lpBufferBuf[0], &pcbBuffer)
+		if ret != 0 || (lastErr != ERROR_INSUFFICIENT_BUFFER && lastErr != ERROR_MORE_DATA) {
+			break
+		}
+		n := 2 * len(lpBufferBuf)
+		if int(pcbBuffer) > len(lpBufferBuf) {
+			n = int(pcbBuffer)
+		}
+		lpBufferBuf = make([]uint16, n)
+	}
+	if int(pcbBuffer) < len(lpBufferBuf) {
+		lpBufferBuf = lpBufferBuf[:pcbBuffer]
+	}
+	lpBuffer = syscall.UTF16ToString(lpBufferBuf)
+	return
+}
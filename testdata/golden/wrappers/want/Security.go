@@ -0,0 +1,62 @@
+// Code generated by go-win32api-gen. DO NOT EDIT.
+
+package win32
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// enums
+
+// enum TOKEN_INFORMATION_CLASS
+type TOKEN_INFORMATION_CLASS int32
+
+const (
+	TokenUser   TOKEN_INFORMATION_CLASS = 1
+	TokenGroups TOKEN_INFORMATION_CLASS = 2
+)
+
+var (
+	pGetTokenInformation uintptr
+)
+
+func GetTokenInformation(TokenHandle HANDLE, TokenInformationClass TOKEN_INFORMATION_CLASS, TokenInformation unsafe.Pointer, TokenInformationLength uint32, ReturnLength *uint32) (BOOL, WIN32_ERROR) {
+	addr := lazyAddr(&pGetTokenInformation, libAdvapi32, "GetTokenInformation")
+	ret, _, err := syscall.SyscallN(addr, TokenHandle, uintptr(TokenInformationClass), uintptr(TokenInformation), uintptr(TokenInformationLength), uintptr(unsafe.Pointer(ReturnLength)))
+	return BOOL(ret), WIN32_ERROR(err)
+}
+
+// GetTokenInformationGo calls GetTokenInformation taking slices for TokenInformation, passing the
+// length of TokenInformation as TokenInformationLength, returning ReturnLength.
+// TokenInformation is optional and may be nil.
+func GetTokenInformationGo(TokenHandle HANDLE, TokenInformationClass TOKEN_INFORMATION_CLASS, TokenInformation []byte) (ReturnLength uint32, ret BOOL, lastErr WIN32_ERROR) {
+	var TokenInformationPtr unsafe.Pointer
+	if len(TokenInformation) > 0 {
+		TokenInformationPtr = unsafe.Pointer(&TokenInformation[0])
+	}
+	ret, lastErr = GetTokenInformation(TokenHandle, TokenInformationClass, TokenInformationPtr, uint32(len(TokenInformation)), &ReturnLength)
+	return
+}
+
+// GetTokenInformationAlloc calls GetTokenInformation returning TokenInformation in a buffer grown
+// while the call fails with ERROR_INSUFFICIENT_BUFFER or ERROR_MORE_DATA, returning ReturnLength.
+func GetTokenInformationAlloc(TokenHandle HANDLE, TokenInformationClass TOKEN_INFORMATION_CLASS) (TokenInformation []byte, ReturnLength uint32, ret BOOL, lastErr WIN32_ERROR) {
+	TokenInformationBuf := make([]byte, 256)
+	for {
+		ret, lastErr = GetTokenInformation(TokenHandle, TokenInformationClass, unsafe.Pointer(&TokenInformationBuf[0]), uint32(len(TokenInformationBuf)), &ReturnLength)
+		if ret != 0 || (lastErr != ERROR_INSUFFICIENT_BUFFER && lastErr != ERROR_MORE_DATA) {
+			break
+		}
+		n := 2 * len(TokenInformationBuf)
+		if int(ReturnLength) > len(TokenInformationBuf) {
+			n = int(ReturnLength)
+		}
+		TokenInformationBuf = make([]byte, n)
+	}
+	if int(ReturnLength) < len(TokenInformationBuf) {
+		TokenInformationBuf = TokenInformationBuf[:ReturnLength]
+	}
+	TokenInformation = TokenInformationBuf
+	return
+}
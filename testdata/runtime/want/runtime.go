@@ -0,0 +1,126 @@
+//go:build amd64 || 386
+
+package win32
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+var (
+	libKernel32 = syscall.NewLazyDLL("kernel32.dll")
+	libUser32   = syscall.NewLazyDLL("user32.dll")
+)
+
+// LazyProcError is the panic value of a call to a procedure
+// that cannot be found in its dll
+type LazyProcError struct {
+	Dll  string
+	Proc string
+	Err  error
+}
+
+func (this *LazyProcError) Error() string {
+	return "failed to find " + this.Proc + " procedure in " + this.Dll + ": " + this.Err.Error()
+}
+
+func (this *LazyProcError) Unwrap() error {
+	return this.Err
+}
+
+// lazyAddr returns the address of a procedure, resolving and caching it in *pAddr
+// on first use. It is safe for concurrent use.
+func lazyAddr(pAddr *uintptr, lib *syscall.LazyDLL, procName string) uintptr {
+	addr := atomic.LoadUintptr(pAddr)
+	if addr != 0 {
+		return addr
+	}
+	err := lib.Load()
+	if err == nil {
+		addr, err = syscall.GetProcAddress(syscall.Handle(lib.Handle()), procName)
+	}
+	if err != nil {
+		panic(&LazyProcError{Dll: lib.Name, Proc: procName, Err: err})
+	}
+	atomic.StoreUintptr(pAddr, addr)
+	return addr
+}
+
+// callbackSlots hands out the callback pointers of one func type.
+// syscall.NewCallback pointers are never released, so a freed slot
+// is reused for the next go function instead of creating a new callback.
+type callbackSlots struct {
+	newThunk func(slots *callbackSlots, slot int) uintptr
+
+	mu    sync.Mutex
+	fns   []interface{}
+	ptrs  []uintptr
+	freed []int
+}
+
+var (
+	callbackMu     sync.Mutex
+	callbackOwners = make(map[uintptr]*callbackSlots)
+)
+
+func (this *callbackSlots) alloc(fn interface{}) uintptr {
+	this.mu.Lock()
+	if n := len(this.freed); n > 0 {
+		slot := this.freed[n-1]
+		this.freed = this.freed[:n-1]
+		this.fns[slot] = fn
+		this.mu.Unlock()
+		return this.ptrs[slot]
+	}
+	slot := len(this.fns)
+	this.fns = append(this.fns, fn)
+	this.ptrs = append(this.ptrs, 0)
+	this.mu.Unlock()
+
+	ptr := this.newThunk(this, slot)
+
+	this.mu.Lock()
+	this.ptrs[slot] = ptr
+	this.mu.Unlock()
+	callbackMu.Lock()
+	callbackOwners[ptr] = this
+	callbackMu.Unlock()
+	return ptr
+}
+
+func (this *callbackSlots) get(slot int) interface{} {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.fns[slot]
+}
+
+func (this *callbackSlots) free(ptr uintptr) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for slot, it := range this.ptrs {
+		if it == ptr && this.fns[slot] != nil {
+			this.fns[slot] = nil
+			this.freed = append(this.freed, slot)
+			return
+		}
+	}
+}
+
+// FreeCallback releases a callback pointer returned by a NewXxx callback constructor,
+// it must not be called by windows afterwards.
+func FreeCallback(ptr uintptr) {
+	callbackMu.Lock()
+	slots := callbackOwners[ptr]
+	callbackMu.Unlock()
+	if slots != nil {
+		slots.free(ptr)
+	}
+}
+
+// HandleLeaked is called with the type name and value of an Owned handle that is
+// garbage collected without being closed. It logs the handle unless it is replaced.
+var HandleLeaked = func(typeName string, handle uintptr) {
+	log.Printf("%s %#x was not closed", typeName, handle)
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Arch is a target architecture, named as in the win32 metadata
+type Arch struct {
+	Name    string
+	GoArch  string
+	PtrSize int
+}
+
+var Arches = []Arch{
+	{Name: "X64", GoArch: "amd64", PtrSize: 8},
+	{Name: "X86", GoArch: "386", PtrSize: 4},
+	{Name: "Arm64", GoArch: "arm64", PtrSize: 8},
+}
+
+// CurArch is the architecture layouts are currently computed for
+var CurArch = Arches[0]
+
+var PtrSize = CurArch.PtrSize
+
+func SetArch(arch Arch) {
+	CurArch = arch
+	PtrSize = arch.PtrSize
+}
+
+func containsArch(arches []Arch, arch Arch) bool {
+	for _, it := range arches {
+		if it.Name == arch.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseArches parses a comma separated list of metadata or GOARCH names, repeated ones once
+func ParseArches(s string) ([]Arch, error) {
+	var arches []Arch
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, arch := range Arches {
+			if strings.EqualFold(name, arch.Name) || name == arch.GoArch {
+				found = true
+				if !containsArch(arches, arch) {
+					arches = append(arches, arch)
+				}
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown architecture %q", name)
+		}
+	}
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("no architecture specified")
+	}
+	return arches, nil
+}
@@ -3,11 +3,8 @@ package utils
 import (
 	"strconv"
 	"strings"
-	"unsafe"
 )
 
-const PtrSize = int(unsafe.Sizeof(uintptr(0)))
-
 func CapName(name string) string {
 	var c uint8
 	for {
@@ -65,9 +62,11 @@ func StructSize(fieldSizes ...SizeInfo) SizeInfo {
 	return SizeInfo{sumSize, maxAlignSize}
 }
 
+// BuildGuidExpr returns the syscall.GUID literal of a GUID string. The fields are keyed,
+// go vet rejects unkeyed literals of struct types of other packages.
 func BuildGuidExpr(sGuid string) string {
-	expr := "syscall.GUID{0x" + sGuid[:8] +
-		", 0x" + sGuid[9:13] + ", 0x" + sGuid[14:18] + ", \n\t[8]byte{"
+	expr := "syscall.GUID{Data1: 0x" + sGuid[:8] +
+		", Data2: 0x" + sGuid[9:13] + ", Data3: 0x" + sGuid[14:18] + ",\n\tData4: [8]byte{"
 	sGuid = strings.Replace(sGuid[19:], "-", "", 1)
 	for n := 0; n < 16; n += 2 {
 		if n > 0 {
@@ -78,3 +77,11 @@ func BuildGuidExpr(sGuid string) string {
 	expr += "}}"
 	return expr
 }
+
+// AlignUp rounds offset up to a multiple of align
+func AlignUp(offset int, align int) int {
+	if align == 0 || offset%align == 0 {
+		return offset
+	}
+	return offset + align - offset%align
+}
@@ -0,0 +1,142 @@
+package winmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// attribute is a decoded custom attribute
+type attribute struct {
+	name      string //without the Attribute suffix
+	namespace string
+	args      []interface{}
+	named     map[string]interface{}
+}
+
+// attributes returns the custom attributes of the row of table
+func (this *reader) attributes(table int, row int) []attribute {
+	var attrs []attribute
+	for _, it := range this.attrRows[encodeIndex(cHasCustomAttribute, table, row)] {
+		attrs = append(attrs, this.attribute(it))
+	}
+	return attrs
+}
+
+// findAttribute returns the custom attribute named name of the row of table
+func (this *reader) findAttribute(table int, row int, name string) (attribute, bool) {
+	for _, it := range this.attributes(table, row) {
+		if it.name == name {
+			return it, true
+		}
+	}
+	return attribute{}, false
+}
+
+// attribute decodes the CustomAttribute row, ECMA-335 II.23.3
+func (this *reader) attribute(row int) attribute {
+	t := &this.md.tables[tCustomAttribute]
+	ctorTable, ctorRow := decodeIndex(cCustomAttributeType, t.get(row, 1))
+	var typeTable, typeRow int
+	var sig []byte
+	switch ctorTable {
+	case tMethodDef:
+		typeTable, typeRow = tTypeDef, this.methodOwners[ctorRow]
+		sig = this.md.blob(this.md.tables[tMethodDef].get(ctorRow, 4))
+	case tMemberRef:
+		typeTable, typeRow = decodeIndex(cMemberRefParent, this.md.tables[tMemberRef].get(ctorRow, 0))
+		sig = this.md.blob(this.md.tables[tMemberRef].get(ctorRow, 2))
+	default:
+		panic(fmt.Errorf("invalid custom attribute constructor %#x", t.get(row, 1)))
+	}
+	name, namespace := this.typeName(typeTable, typeRow)
+	attr := attribute{
+		name:      strings.TrimSuffix(name, "Attribute"),
+		namespace: namespace,
+		named:     make(map[string]interface{}),
+	}
+
+	ctor := (&sigReader{sig}).methodSig()
+	r := &sigReader{this.md.blob(t.get(row, 2))}
+	if len(r.p) == 0 {
+		return attr
+	}
+	if prolog := r.fixed(2); prolog != attrBlobProlog {
+		panic(fmt.Errorf("invalid custom attribute prolog %#x", prolog))
+	}
+	for _, it := range ctor.params {
+		attr.args = append(attr.args, this.attrValue(r, it))
+	}
+	for n := r.fixed(2); n > 0; n-- {
+		r.byte() //field or property
+		elem := r.byte()
+		if elem == elemEnum {
+			//the underlying type of enums named by their type name is not known,
+			//they are 32 bit in the metadata
+			r.serString()
+			elem = elemI4
+		}
+		name := r.serString()
+		attr.named[name] = r.elemValue(elem)
+	}
+	return attr
+}
+
+// attrValue reads a fixed argument of type t
+func (this *reader) attrValue(r *sigReader, t *sigType) interface{} {
+	switch t.elem {
+	case elemValueType:
+		return r.elemValue(this.enumBase(t.table, t.row))
+	case elemClass:
+		//System.Type
+		return r.serString()
+	case elemSzArray:
+		var values []interface{}
+		if n := r.fixed(4); n != 0xffffffff {
+			for ; n > 0; n-- {
+				values = append(values, this.attrValue(r, t.child))
+			}
+		}
+		return values
+	case elemObject:
+		return r.elemValue(elemBoxed)
+	default:
+		return r.elemValue(t.elem)
+	}
+}
+
+// enumBase returns the element type of the values of an enum,
+// those of enums that are not declared in the metadata are assumed to be 32 bit
+func (this *reader) enumBase(table int, row int) byte {
+	if table == tTypeDef {
+		start, end := this.md.rowRange(tTypeDef, row, 4, tField)
+		for it := start; it < end; it++ {
+			if this.md.tables[tField].get(it, 0)&fieldStatic == 0 {
+				return this.fieldType(it).elem
+			}
+		}
+	}
+	return elemI4
+}
+
+// intArg returns the integer argument n of the attribute
+func (this *attribute) intArg(n int) int64 {
+	if v, ok := this.args[n].(int64); ok {
+		return v
+	}
+	return 0
+}
+
+// stringArg returns the string argument n of the attribute
+func (this *attribute) stringArg(n int) string {
+	s, _ := this.args[n].(string)
+	return s
+}
+
+// guid formats the arguments of a Guid attribute starting at n as a guid
+func (this *attribute) guid(n int) string {
+	var parts []interface{}
+	for m := n; m < n+11; m++ {
+		parts = append(parts, this.intArg(m))
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%02x%02x-%02x%02x%02x%02x%02x%02x", parts...)
+}
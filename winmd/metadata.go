@@ -0,0 +1,388 @@
+package winmd
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// metadata tables, ECMA-335 II.22
+const (
+	tModule                 = 0x00
+	tTypeRef                = 0x01
+	tTypeDef                = 0x02
+	tFieldPtr               = 0x03
+	tField                  = 0x04
+	tMethodPtr              = 0x05
+	tMethodDef              = 0x06
+	tParamPtr               = 0x07
+	tParam                  = 0x08
+	tInterfaceImpl          = 0x09
+	tMemberRef              = 0x0a
+	tConstant               = 0x0b
+	tCustomAttribute        = 0x0c
+	tFieldMarshal           = 0x0d
+	tDeclSecurity           = 0x0e
+	tClassLayout            = 0x0f
+	tFieldLayout            = 0x10
+	tStandAloneSig          = 0x11
+	tEventMap               = 0x12
+	tEventPtr               = 0x13
+	tEvent                  = 0x14
+	tPropertyMap            = 0x15
+	tPropertyPtr            = 0x16
+	tProperty               = 0x17
+	tMethodSemantics        = 0x18
+	tMethodImpl             = 0x19
+	tModuleRef              = 0x1a
+	tTypeSpec               = 0x1b
+	tImplMap                = 0x1c
+	tFieldRVA               = 0x1d
+	tEncLog                 = 0x1e
+	tEncMap                 = 0x1f
+	tAssembly               = 0x20
+	tAssemblyProcessor      = 0x21
+	tAssemblyOS             = 0x22
+	tAssemblyRef            = 0x23
+	tAssemblyRefProcessor   = 0x24
+	tAssemblyRefOS          = 0x25
+	tFile                   = 0x26
+	tExportedType           = 0x27
+	tManifestResource       = 0x28
+	tNestedClass            = 0x29
+	tGenericParam           = 0x2a
+	tMethodSpec             = 0x2b
+	tGenericParamConstraint = 0x2c
+	tableCount              = 0x2d
+)
+
+// column kinds, other than table indexes which are the table numbers
+const (
+	cU16 = 0x100 + iota
+	cU32
+	cString
+	cGuid
+	cBlob
+	cCoded //coded indexes are cCoded + their index in codedIndexes
+)
+
+// coded indexes, ECMA-335 II.24.2.6, -1 for the unused tags
+var codedIndexes = [][]int{
+	cTypeDefOrRef:        {tTypeDef, tTypeRef, tTypeSpec},
+	cHasConstant:         {tField, tParam, tProperty},
+	cHasCustomAttribute:  {tMethodDef, tField, tTypeRef, tTypeDef, tParam, tInterfaceImpl, tMemberRef, tModule, tDeclSecurity, tProperty, tEvent, tStandAloneSig, tModuleRef, tTypeSpec, tAssembly, tAssemblyRef, tFile, tExportedType, tManifestResource, tGenericParam, tGenericParamConstraint, tMethodSpec},
+	cHasFieldMarshal:     {tField, tParam},
+	cHasDeclSecurity:     {tTypeDef, tMethodDef, tAssembly},
+	cMemberRefParent:     {tTypeDef, tTypeRef, tModuleRef, tMethodDef, tTypeSpec},
+	cHasSemantics:        {tEvent, tProperty},
+	cMethodDefOrRef:      {tMethodDef, tMemberRef},
+	cMemberForwarded:     {tField, tMethodDef},
+	cImplementation:      {tFile, tAssemblyRef, tExportedType},
+	cCustomAttributeType: {-1, -1, tMethodDef, tMemberRef, -1},
+	cResolutionScope:     {tModule, tModuleRef, tAssemblyRef, tTypeRef},
+	cTypeOrMethodDef:     {tTypeDef, tMethodDef},
+}
+
+const (
+	cTypeDefOrRef = iota
+	cHasConstant
+	cHasCustomAttribute
+	cHasFieldMarshal
+	cHasDeclSecurity
+	cMemberRefParent
+	cHasSemantics
+	cMethodDefOrRef
+	cMemberForwarded
+	cImplementation
+	cCustomAttributeType
+	cResolutionScope
+	cTypeOrMethodDef
+)
+
+func coded(n int) int {
+	return cCoded + n
+}
+
+// the columns of the tables, ECMA-335 II.22
+var tableSchemas = [tableCount][]int{
+	tModule:                 {cU16, cString, cGuid, cGuid, cGuid},
+	tTypeRef:                {coded(cResolutionScope), cString, cString},
+	tTypeDef:                {cU32, cString, cString, coded(cTypeDefOrRef), tField, tMethodDef},
+	tFieldPtr:               {tField},
+	tField:                  {cU16, cString, cBlob},
+	tMethodPtr:              {tMethodDef},
+	tMethodDef:              {cU32, cU16, cU16, cString, cBlob, tParam},
+	tParamPtr:               {tParam},
+	tParam:                  {cU16, cU16, cString},
+	tInterfaceImpl:          {tTypeDef, coded(cTypeDefOrRef)},
+	tMemberRef:              {coded(cMemberRefParent), cString, cBlob},
+	tConstant:               {cU16, coded(cHasConstant), cBlob},
+	tCustomAttribute:        {coded(cHasCustomAttribute), coded(cCustomAttributeType), cBlob},
+	tFieldMarshal:           {coded(cHasFieldMarshal), cBlob},
+	tDeclSecurity:           {cU16, coded(cHasDeclSecurity), cBlob},
+	tClassLayout:            {cU16, cU32, tTypeDef},
+	tFieldLayout:            {cU32, tField},
+	tStandAloneSig:          {cBlob},
+	tEventMap:               {tTypeDef, tEvent},
+	tEventPtr:               {tEvent},
+	tEvent:                  {cU16, cString, coded(cTypeDefOrRef)},
+	tPropertyMap:            {tTypeDef, tProperty},
+	tPropertyPtr:            {tProperty},
+	tProperty:               {cU16, cString, cBlob},
+	tMethodSemantics:        {cU16, tMethodDef, coded(cHasSemantics)},
+	tMethodImpl:             {tTypeDef, coded(cMethodDefOrRef), coded(cMethodDefOrRef)},
+	tModuleRef:              {cString},
+	tTypeSpec:               {cBlob},
+	tImplMap:                {cU16, coded(cMemberForwarded), cString, tModuleRef},
+	tFieldRVA:               {cU32, tField},
+	tEncLog:                 {cU32, cU32},
+	tEncMap:                 {cU32},
+	tAssembly:               {cU32, cU16, cU16, cU16, cU16, cU32, cBlob, cString, cString},
+	tAssemblyProcessor:      {cU32},
+	tAssemblyOS:             {cU32, cU32, cU32},
+	tAssemblyRef:            {cU16, cU16, cU16, cU16, cU32, cBlob, cString, cString, cBlob},
+	tAssemblyRefProcessor:   {cU32, tAssemblyRef},
+	tAssemblyRefOS:          {cU32, cU32, cU32, tAssemblyRef},
+	tFile:                   {cU32, cString, cBlob},
+	tExportedType:           {cU32, cU32, cString, cString, coded(cImplementation)},
+	tManifestResource:       {cU32, cU32, cString, coded(cImplementation)},
+	tNestedClass:            {tTypeDef, tTypeDef},
+	tGenericParam:           {cU16, cU16, coded(cTypeOrMethodDef), cString},
+	tMethodSpec:             {coded(cMethodDefOrRef), cBlob},
+	tGenericParamConstraint: {tGenericParam, coded(cTypeDefOrRef)},
+}
+
+type table struct {
+	rows    int
+	rowSize int
+	offsets []int
+	sizes   []int
+	data    []byte
+}
+
+// get returns column col of row, rows are numbered from 1 as in the metadata
+func (this *table) get(row int, col int) uint32 {
+	p := this.data[(row-1)*this.rowSize+this.offsets[col]:]
+	switch this.sizes[col] {
+	case 1:
+		return uint32(p[0])
+	case 2:
+		return uint32(binary.LittleEndian.Uint16(p))
+	default:
+		return binary.LittleEndian.Uint32(p)
+	}
+}
+
+// metadata is the metadata of a CLI module, ECMA-335 II.24
+type metadata struct {
+	strings []byte
+	blobs   []byte
+	tables  [tableCount]table
+}
+
+// readMetadata reads the metadata of the CLI module in the PE image data
+func readMetadata(data []byte) (*metadata, error) {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var dirs []pe.DataDirectory
+	var dirCount uint32
+	switch h := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs, dirCount = h.DataDirectory[:], h.NumberOfRvaAndSizes
+	case *pe.OptionalHeader64:
+		dirs, dirCount = h.DataDirectory[:], h.NumberOfRvaAndSizes
+	}
+	const cliHeaderDir = 14
+	if dirCount <= cliHeaderDir || dirs[cliHeaderDir].VirtualAddress == 0 {
+		return nil, errors.New("not a CLI module")
+	}
+	cliHeader, err := readRva(f, dirs[cliHeaderDir].VirtualAddress, dirs[cliHeaderDir].Size)
+	if err != nil {
+		return nil, err
+	}
+	if len(cliHeader) < 16 {
+		return nil, errors.New("invalid CLI header")
+	}
+	root, err := readRva(f, binary.LittleEndian.Uint32(cliHeader[8:]), binary.LittleEndian.Uint32(cliHeader[12:]))
+	if err != nil {
+		return nil, err
+	}
+	return parseMetadata(root)
+}
+
+// readRva returns size bytes at the relative virtual address rva of the image
+func readRva(f *pe.File, rva uint32, size uint32) ([]byte, error) {
+	for _, s := range f.Sections {
+		if rva >= s.VirtualAddress && rva+size <= s.VirtualAddress+s.VirtualSize {
+			data, err := s.Data()
+			if err != nil {
+				return nil, err
+			}
+			start := rva - s.VirtualAddress
+			if int(start+size) > len(data) {
+				break
+			}
+			return data[start : start+size], nil
+		}
+	}
+	return nil, fmt.Errorf("rva %#x is not mapped", rva)
+}
+
+// parseMetadata parses the metadata root and the streams it points to
+func parseMetadata(root []byte) (md *metadata, err error) {
+	defer func() {
+		//slices of truncated metadata are out of range
+		if r := recover(); r != nil {
+			md, err = nil, fmt.Errorf("invalid metadata: %v", r)
+		}
+	}()
+	if len(root) < 16 || binary.LittleEndian.Uint32(root) != 0x424a5342 {
+		return nil, errors.New("invalid metadata signature")
+	}
+	versionLen := int(binary.LittleEndian.Uint32(root[12:]))
+	p := root[16+versionLen:]
+	streamCount := int(binary.LittleEndian.Uint16(p[2:]))
+	p = p[4:]
+	md = &metadata{}
+	var tableStream []byte
+	for n := 0; n < streamCount; n++ {
+		offset := binary.LittleEndian.Uint32(p)
+		size := binary.LittleEndian.Uint32(p[4:])
+		name := p[8:]
+		name = name[:bytes.IndexByte(name, 0)]
+		p = p[8+(len(name)+4)&^3:]
+		stream := root[offset : offset+size]
+		switch string(name) {
+		case "#~":
+			tableStream = stream
+		case "#-":
+			return nil, errors.New("uncompressed metadata tables are not supported")
+		case "#Strings":
+			md.strings = stream
+		case "#Blob":
+			md.blobs = stream
+		}
+	}
+	if tableStream == nil {
+		return nil, errors.New("no metadata tables")
+	}
+	md.parseTables(tableStream)
+	return md, nil
+}
+
+// parseTables lays out the tables of the #~ stream, ECMA-335 II.24.2.6
+func (this *metadata) parseTables(p []byte) {
+	heapSizes := p[6]
+	valid := binary.LittleEndian.Uint64(p[8:])
+	p = p[24:]
+	for n := 0; n < 64; n++ {
+		if valid&(1<<n) == 0 {
+			continue
+		}
+		if n < tableCount {
+			this.tables[n].rows = int(binary.LittleEndian.Uint32(p))
+		}
+		p = p[4:]
+	}
+	if heapSizes&0x40 != 0 {
+		//extra data
+		p = p[4:]
+	}
+	heapIndexSize := func(flag byte) int {
+		if heapSizes&flag != 0 {
+			return 4
+		}
+		return 2
+	}
+	for n := 0; n < tableCount; n++ {
+		t := &this.tables[n]
+		for _, col := range tableSchemas[n] {
+			var size int
+			switch {
+			case col == cU16:
+				size = 2
+			case col == cU32:
+				size = 4
+			case col == cString:
+				size = heapIndexSize(0x01)
+			case col == cGuid:
+				size = heapIndexSize(0x02)
+			case col == cBlob:
+				size = heapIndexSize(0x04)
+			case col >= cCoded:
+				size = this.codedIndexSize(codedIndexes[col-cCoded])
+			default:
+				size = 2
+				if this.tables[col].rows > 0xffff {
+					size = 4
+				}
+			}
+			t.offsets = append(t.offsets, t.rowSize)
+			t.sizes = append(t.sizes, size)
+			t.rowSize += size
+		}
+		t.data = p[:t.rows*t.rowSize]
+		p = p[t.rows*t.rowSize:]
+	}
+}
+
+func (this *metadata) codedIndexSize(tables []int) int {
+	tagBits := bits.Len(uint(len(tables) - 1))
+	for _, it := range tables {
+		if it >= 0 && this.tables[it].rows >= 1<<(16-tagBits) {
+			return 4
+		}
+	}
+	return 2
+}
+
+// decodeIndex splits the coded index value of kind into its table and row
+func decodeIndex(kind int, value uint32) (int, int) {
+	tables := codedIndexes[kind]
+	tagBits := bits.Len(uint(len(tables) - 1))
+	tag := int(value & (1<<tagBits - 1))
+	if tag >= len(tables) {
+		return -1, 0
+	}
+	return tables[tag], int(value >> tagBits)
+}
+
+// encodeIndex returns the coded index of kind of the row of table
+func encodeIndex(kind int, table int, row int) uint32 {
+	tables := codedIndexes[kind]
+	tagBits := bits.Len(uint(len(tables) - 1))
+	for tag, it := range tables {
+		if it == table {
+			return uint32(row)<<tagBits | uint32(tag)
+		}
+	}
+	panic(fmt.Sprintf("table %#x has no %d coded index", table, kind))
+}
+
+func (this *metadata) string(index uint32) string {
+	p := this.strings[index:]
+	return string(p[:bytes.IndexByte(p, 0)])
+}
+
+func (this *metadata) blob(index uint32) []byte {
+	p := this.blobs[index:]
+	size, n := uncompress(p)
+	return p[n : n+int(size)]
+}
+
+// rowRange returns the rows of table listed from column col of row of the owner table,
+// up to the list of the next row
+func (this *metadata) rowRange(owner int, row int, col int, table int) (int, int) {
+	t := &this.tables[owner]
+	start := int(t.get(row, col))
+	end := this.tables[table].rows + 1
+	if row < t.rows {
+		end = int(t.get(row+1, col))
+	}
+	return start, end
+}
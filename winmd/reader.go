@@ -0,0 +1,719 @@
+// Package winmd reads the Win32 metadata from Windows.Win32.winmd, the ECMA-335 metadata
+// the win32json files are generated from, into the same jsonmodel declarations.
+package winmd
+
+import (
+	"fmt"
+	"go-win32api-gen/jsonmodel"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// the namespace prefix of the declarations, api names are the namespaces without it
+const nsPrefix = "Windows.Win32."
+
+// the namespace of the attributes describing the declarations
+const attrNamespace = nsPrefix + "Foundation.Metadata"
+
+// TypeDef, Field, MethodDef, Param and ImplMap flags, ECMA-335 II.23.1
+const (
+	typeInterface      = 0x20
+	typeLayoutMask     = 0x18
+	typeExplicitLayout = 0x10
+	fieldStatic        = 0x10
+	methodSpecialName  = 0x800
+	paramIn            = 0x01
+	paramOut           = 0x02
+	paramOptional      = 0x10
+	implMapLastError   = 0x40
+)
+
+// the architectures of SupportedArchitecture by bit
+var archNames = []string{"X86", "X64", "Arm64"}
+
+// the names of the arguments of the attributes passed as Props
+var attrArgNames = map[string][]string{
+	"FreeWith": {"Func"},
+}
+
+// reader converts the declarations of a winmd file
+type reader struct {
+	md   *metadata
+	path string
+
+	attrRows     map[uint32][]int //CustomAttribute rows by HasCustomAttribute index
+	constants    map[uint32]int   //Constant rows by HasConstant index
+	layouts      map[int]int      //ClassLayout rows by TypeDef row
+	implMaps     map[int]int      //ImplMap rows by MethodDef row
+	interfaces   map[int][]int    //InterfaceImpl rows by TypeDef row
+	nested       map[int][]int    //nested TypeDef rows by enclosing TypeDef row
+	enclosing    map[int]int      //enclosing TypeDef rows by nested TypeDef row
+	methodOwners map[int]int      //TypeDef rows by MethodDef row
+}
+
+// cachedMetadata is the metadata of a winmd file with the modification time and size it was read at
+type cachedMetadata struct {
+	modTime time.Time
+	size    int64
+	md      *metadata
+}
+
+// the metadata of the winmd files read, by path: the declarations are read once per arch,
+// the file is read again when it changes
+var (
+	mdCacheMu sync.Mutex
+	mdCache   = make(map[string]cachedMetadata)
+)
+
+// LoadApis reads the declarations of the winmd file like jsonmodel.LoadApis
+// reads them from the win32json files
+func LoadApis(path string) ([]*jsonmodel.Api, error) {
+	apis, err := ReadApis(path)
+	if err != nil {
+		return nil, err
+	}
+	return jsonmodel.PrepareApis(apis), nil
+}
+
+// ReadApis reads the declarations of the winmd file by namespace,
+// they are not prepared for code generation
+func ReadApis(path string) (apis []*jsonmodel.Api, err error) {
+	md, err := loadMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			apis, err = nil, fmt.Errorf("%s: invalid metadata: %v", path, e)
+		}
+	}()
+	r := newReader(md, path)
+	return r.readApis(), nil
+}
+
+// loadMetadata returns the metadata of the winmd file, cached while the file is unchanged
+func loadMetadata(path string) (*metadata, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	mdCacheMu.Lock()
+	defer mdCacheMu.Unlock()
+	if c, ok := mdCache[path]; ok && c.modTime.Equal(fi.ModTime()) && c.size == fi.Size() {
+		return c.md, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	md, err := readMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	mdCache[path] = cachedMetadata{modTime: fi.ModTime(), size: fi.Size(), md: md}
+	return md, nil
+}
+
+func newReader(md *metadata, path string) *reader {
+	r := &reader{
+		md:           md,
+		path:         path,
+		attrRows:     make(map[uint32][]int),
+		constants:    make(map[uint32]int),
+		layouts:      make(map[int]int),
+		implMaps:     make(map[int]int),
+		interfaces:   make(map[int][]int),
+		nested:       make(map[int][]int),
+		enclosing:    make(map[int]int),
+		methodOwners: make(map[int]int),
+	}
+	tables := &md.tables
+	for row := 1; row <= tables[tCustomAttribute].rows; row++ {
+		parent := tables[tCustomAttribute].get(row, 0)
+		r.attrRows[parent] = append(r.attrRows[parent], row)
+	}
+	for row := 1; row <= tables[tConstant].rows; row++ {
+		r.constants[tables[tConstant].get(row, 1)] = row
+	}
+	for row := 1; row <= tables[tClassLayout].rows; row++ {
+		r.layouts[int(tables[tClassLayout].get(row, 2))] = row
+	}
+	for row := 1; row <= tables[tImplMap].rows; row++ {
+		if table, method := decodeIndex(cMemberForwarded, tables[tImplMap].get(row, 1)); table == tMethodDef {
+			r.implMaps[method] = row
+		}
+	}
+	for row := 1; row <= tables[tInterfaceImpl].rows; row++ {
+		class := int(tables[tInterfaceImpl].get(row, 0))
+		r.interfaces[class] = append(r.interfaces[class], row)
+	}
+	for row := 1; row <= tables[tNestedClass].rows; row++ {
+		nested := int(tables[tNestedClass].get(row, 0))
+		enclosing := int(tables[tNestedClass].get(row, 1))
+		r.nested[enclosing] = append(r.nested[enclosing], nested)
+		r.enclosing[nested] = enclosing
+	}
+	for row := 1; row <= tables[tTypeDef].rows; row++ {
+		start, end := md.rowRange(tTypeDef, row, 5, tMethodDef)
+		for it := start; it < end; it++ {
+			r.methodOwners[it] = row
+		}
+	}
+	for _, rows := range r.nested {
+		sort.Ints(rows)
+	}
+	return r
+}
+
+// readApis converts the declarations of the namespaces under nsPrefix, sorted by name.
+// Functions and constants are those of the Apis classes.
+func (this *reader) readApis() []*jsonmodel.Api {
+	apiMap := make(map[string]*jsonmodel.Api)
+	var apis []*jsonmodel.Api
+	for row := 1; row <= this.md.tables[tTypeDef].rows; row++ {
+		if this.enclosing[row] != 0 {
+			continue
+		}
+		name, ns := this.typeName(tTypeDef, row)
+		if !strings.HasPrefix(ns, nsPrefix) || ns == attrNamespace {
+			continue
+		}
+		api := apiMap[ns]
+		if api == nil {
+			api = &jsonmodel.Api{Name: strings.TrimPrefix(ns, nsPrefix), File: this.path}
+			apiMap[ns] = api
+			apis = append(apis, api)
+		}
+		if name == "Apis" {
+			this.readApisClass(row, api)
+		} else if t := this.readType(row); t != nil {
+			api.Types = append(api.Types, t)
+		}
+	}
+	for _, api := range apis {
+		api.UnicodeAliases = unicodeAliases(api)
+	}
+	sort.Slice(apis, func(i, j int) bool {
+		return apis[i].Name < apis[j].Name
+	})
+	return apis
+}
+
+// unicodeAliases returns the names of the types and functions declared with both
+// an A and a W suffix
+func unicodeAliases(api *jsonmodel.Api) []string {
+	var names []string
+	for _, t := range api.Types {
+		names = append(names, t.Name)
+	}
+	for _, f := range api.Functions {
+		names = append(names, f.Name)
+	}
+	declared := make(map[string]bool)
+	for _, it := range names {
+		declared[it] = true
+	}
+	aliases := []string{}
+	added := make(map[string]bool)
+	for _, it := range names {
+		if !strings.HasSuffix(it, "W") {
+			continue
+		}
+		alias := it[:len(it)-1]
+		if declared[alias+"A"] && !added[alias] {
+			added[alias] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// typeName returns the name and namespace of a TypeDef or TypeRef row,
+// nested types have the namespace of the types they are nested in
+func (this *reader) typeName(table int, row int) (string, string) {
+	t := &this.md.tables[table]
+	name := this.md.string(t.get(row, 1))
+	ns := this.md.string(t.get(row, 2))
+	switch table {
+	case tTypeDef:
+		if enclosing, ok := this.enclosing[row]; ok {
+			_, ns = this.typeName(tTypeDef, enclosing)
+		}
+	case tTypeRef:
+		if scopeTable, scopeRow := decodeIndex(cResolutionScope, t.get(row, 0)); scopeTable == tTypeRef {
+			_, ns = this.typeName(tTypeRef, scopeRow)
+		}
+	case tTypeSpec:
+		panic(fmt.Errorf("type specs are not supported"))
+	}
+	return name, ns
+}
+
+// baseType returns the fq name of the type the TypeDef row extends
+func (this *reader) baseType(row int) string {
+	table, baseRow := decodeIndex(cTypeDefOrRef, this.md.tables[tTypeDef].get(row, 3))
+	if baseRow == 0 {
+		return ""
+	}
+	name, ns := this.typeName(table, baseRow)
+	return ns + "." + name
+}
+
+func (this *reader) fieldType(row int) *sigType {
+	return (&sigReader{this.md.blob(this.md.tables[tField].get(row, 2))}).fieldSig()
+}
+
+// architectures returns the architectures of the SupportedArchitecture attribute
+// of the row of table, none if it is supported on all of them
+func (this *reader) architectures(table int, row int) []string {
+	attr, ok := this.findAttribute(table, row, "SupportedArchitecture")
+	if !ok {
+		return nil
+	}
+	var arches []string
+	for n, it := range archNames {
+		if attr.intArg(0)&(1<<n) != 0 {
+			arches = append(arches, it)
+		}
+	}
+	return arches
+}
+
+func (this *reader) platform(table int, row int) string {
+	attr, ok := this.findAttribute(table, row, "SupportedOSPlatform")
+	if !ok {
+		return ""
+	}
+	return attr.stringArg(0)
+}
+
+// readType converts the TypeDef row, it returns nil for the classes that are not declarations
+func (this *reader) readType(row int) *jsonmodel.Type {
+	td := &this.md.tables[tTypeDef]
+	flags := td.get(row, 0)
+	name, _ := this.typeName(tTypeDef, row)
+	t := &jsonmodel.Type{
+		Name:          name,
+		Architectures: this.architectures(tTypeDef, row),
+		Platform:      this.platform(tTypeDef, row),
+	}
+	base := this.baseType(row)
+	switch {
+	case flags&typeInterface != 0:
+		this.readCom(row, t)
+	case base == "System.Enum":
+		this.readEnum(row, t)
+	case base == "System.MulticastDelegate":
+		this.readFunctionPointer(row, t)
+	case base == "System.ValueType":
+		if _, ok := this.findAttribute(tTypeDef, row, "NativeTypedef"); ok {
+			this.readNativeTypedef(row, t)
+		} else {
+			this.readStruct(row, t)
+		}
+	default:
+		attr, ok := this.findAttribute(tTypeDef, row, "Guid")
+		if !ok {
+			return nil
+		}
+		t.Kind = "ComClassID"
+		t.Guid = attr.guid(0)
+	}
+	return t
+}
+
+func (this *reader) readCom(row int, t *jsonmodel.Type) {
+	t.Kind = "Com"
+	if attr, ok := this.findAttribute(tTypeDef, row, "Guid"); ok {
+		t.Guid = attr.guid(0)
+	}
+	for _, it := range this.interfaces[row] {
+		table, ifRow := decodeIndex(cTypeDefOrRef, this.md.tables[tInterfaceImpl].get(it, 1))
+		t.Interface = this.typeRef(&sigType{elem: elemClass, table: table, row: ifRow})
+		break
+	}
+	start, end := this.md.rowRange(tTypeDef, row, 5, tMethodDef)
+	for it := start; it < end; it++ {
+		t.Methods = append(t.Methods, this.readFunction(it))
+	}
+}
+
+func (this *reader) readEnum(row int, t *jsonmodel.Type) {
+	t.Kind = "Enum"
+	_, t.Flags = this.findAttribute(tTypeDef, row, "Flags")
+	_, t.Scoped = this.findAttribute(tTypeDef, row, "ScopedEnum")
+	start, end := this.md.rowRange(tTypeDef, row, 4, tField)
+	for it := start; it < end; it++ {
+		if this.md.tables[tField].get(it, 0)&fieldStatic == 0 {
+			t.IntegerBase = nativeTypeNames[this.fieldType(it).elem]
+			continue
+		}
+		value := struct {
+			Name  string
+			Value big.Int
+		}{Name: this.md.string(this.md.tables[tField].get(it, 1))}
+		v, _ := this.constant(tField, it)
+		switch v := v.(type) {
+		case int64:
+			value.Value.SetInt64(v)
+		case uint64:
+			value.Value.SetUint64(v)
+		}
+		t.Values = append(t.Values, value)
+	}
+}
+
+func (this *reader) readFunctionPointer(row int, t *jsonmodel.Type) {
+	t.Kind = "FunctionPointer"
+	if attr, ok := this.findAttribute(tTypeDef, row, "UnmanagedFunctionPointer"); ok {
+		t.SetLastError, _ = attr.named["SetLastError"].(bool)
+	}
+	start, end := this.md.rowRange(tTypeDef, row, 5, tMethodDef)
+	for it := start; it < end; it++ {
+		if this.md.string(this.md.tables[tMethodDef].get(it, 3)) != "Invoke" {
+			continue
+		}
+		f := this.readFunction(it)
+		t.ReturnType = f.ReturnType
+		for _, p := range f.Params {
+			t.Params = append(t.Params, &struct {
+				Name  string
+				Type  *jsonmodel.Type
+				Attrs []jsonmodel.Attr
+			}{p.Name, p.Type, p.Attrs})
+		}
+	}
+}
+
+// readNativeTypedef converts a struct with the NativeTypedef attribute,
+// its Value field has the type it is defined as
+func (this *reader) readNativeTypedef(row int, t *jsonmodel.Type) {
+	t.Kind = "NativeTypedef"
+	start, end := this.md.rowRange(tTypeDef, row, 4, tField)
+	if start < end {
+		t.Def = this.typeRef(this.fieldType(start))
+	}
+	if attr, ok := this.findAttribute(tTypeDef, row, "AlsoUsableFor"); ok {
+		t.AlsoUsableFor = attr.stringArg(0)
+	}
+	if attr, ok := this.findAttribute(tTypeDef, row, "RAIIFree"); ok {
+		t.FreeFunc = attr.stringArg(0)
+	}
+}
+
+func (this *reader) readStruct(row int, t *jsonmodel.Type) {
+	t.Kind = "Struct"
+	if this.md.tables[tTypeDef].get(row, 0)&typeLayoutMask == typeExplicitLayout {
+		t.Kind = "Union"
+	}
+	if layout, ok := this.layouts[row]; ok {
+		t.PackingSize = int(this.md.tables[tClassLayout].get(layout, 0))
+		t.Size = int(this.md.tables[tClassLayout].get(layout, 1))
+	}
+	start, end := this.md.rowRange(tTypeDef, row, 4, tField)
+	for it := start; it < end; it++ {
+		if this.md.tables[tField].get(it, 0)&fieldStatic != 0 {
+			continue
+		}
+		attrs, _ := this.declAttrs(tField, it)
+		t.Fields = append(t.Fields, &struct {
+			Name  string
+			Type  *jsonmodel.Type
+			Attrs []jsonmodel.Attr
+		}{
+			Name:  this.md.string(this.md.tables[tField].get(it, 1)),
+			Type:  this.typeRef(this.fieldType(it)),
+			Attrs: attrs,
+		})
+	}
+	for _, it := range this.nested[row] {
+		if nt := this.readType(it); nt != nil {
+			t.NestedTypes = append(t.NestedTypes, nt)
+		}
+	}
+}
+
+// readApisClass converts the methods of an Apis class imported from dlls to functions,
+// and its static fields with a value to constants
+func (this *reader) readApisClass(row int, api *jsonmodel.Api) {
+	start, end := this.md.rowRange(tTypeDef, row, 5, tMethodDef)
+	for it := start; it < end; it++ {
+		if _, ok := this.implMaps[it]; ok {
+			api.Functions = append(api.Functions, this.readFunction(it))
+		}
+	}
+	start, end = this.md.rowRange(tTypeDef, row, 4, tField)
+	for it := start; it < end; it++ {
+		if c := this.readConstant(it); c != nil {
+			api.Constants = append(api.Constants, c)
+		}
+	}
+}
+
+// readFunction converts the MethodDef row, with the dll it is imported from if any
+func (this *reader) readFunction(row int) *jsonmodel.Function {
+	md := &this.md.tables[tMethodDef]
+	f := &jsonmodel.Function{
+		Name:          this.md.string(md.get(row, 3)),
+		Architectures: this.architectures(tMethodDef, row),
+		Platform:      this.platform(tMethodDef, row),
+	}
+	if md.get(row, 2)&methodSpecialName != 0 {
+		f.Attrs = append(f.Attrs, jsonmodel.Attr{Str: "SpecialName"})
+	}
+	if implMap, ok := this.implMaps[row]; ok {
+		im := &this.md.tables[tImplMap]
+		f.SetLastError = im.get(implMap, 0)&implMapLastError != 0
+		dll := this.md.string(this.md.tables[tModuleRef].get(int(im.get(implMap, 3)), 0))
+		if strings.HasSuffix(strings.ToLower(dll), ".dll") {
+			dll = dll[:len(dll)-len(".dll")]
+		}
+		f.DllImport = dll
+	}
+
+	sig := (&sigReader{this.md.blob(md.get(row, 4))}).methodSig()
+	f.ReturnType = this.typeRef(sig.ret)
+	paramRows := make(map[int]int)
+	start, end := this.md.rowRange(tMethodDef, row, 5, tParam)
+	for it := start; it < end; it++ {
+		paramRows[int(this.md.tables[tParam].get(it, 1))] = it
+	}
+	if it, ok := paramRows[0]; ok {
+		f.ReturnAttrs = this.paramAttrs(it)
+	}
+	for n, pt := range sig.params {
+		p := jsonmodel.Param{
+			Name: "param" + strconv.Itoa(n),
+			Type: this.typeRef(pt),
+		}
+		if it, ok := paramRows[n+1]; ok {
+			p.Name = this.md.string(this.md.tables[tParam].get(it, 2))
+			p.Attrs = this.paramAttrs(it)
+			if attr, ok := this.findAttribute(tParam, it, "NativeArrayInfo"); ok {
+				p.Type = lpArray(p.Type, attr)
+			}
+		}
+		f.Params = append(f.Params, p)
+	}
+	return f
+}
+
+// lpArray converts the pointer type t of a parameter with the NativeArrayInfo attribute
+// to an LPArray of its element type
+func lpArray(t *jsonmodel.Type, attr attribute) *jsonmodel.Type {
+	if t.Kind != "PointerTo" {
+		return t
+	}
+	arr := &jsonmodel.Type{
+		Kind:            "LPArray",
+		CountConst:      -1,
+		CountParamIndex: -1,
+		Child:           t.Child,
+	}
+	if v, ok := attr.named["CountConst"].(int64); ok {
+		arr.CountConst = int(v)
+	}
+	if v, ok := attr.named["CountParamIndex"].(int64); ok {
+		arr.CountParamIndex = int(v)
+	}
+	return arr
+}
+
+// paramAttrs returns the attributes of the Param row in the order of the win32json
+// files: In and Out, the metadata attributes, Optional and Const
+func (this *reader) paramAttrs(row int) []jsonmodel.Attr {
+	var attrs []jsonmodel.Attr
+	flags := this.md.tables[tParam].get(row, 0)
+	if flags&paramIn != 0 {
+		attrs = append(attrs, jsonmodel.Attr{Str: "In"})
+	}
+	if flags&paramOut != 0 {
+		attrs = append(attrs, jsonmodel.Attr{Str: "Out"})
+	}
+	declAttrs, isConst := this.declAttrs(tParam, row)
+	attrs = append(attrs, declAttrs...)
+	if flags&paramOptional != 0 {
+		attrs = append(attrs, jsonmodel.Attr{Str: "Optional"})
+	}
+	if isConst {
+		attrs = append(attrs, jsonmodel.Attr{Str: "Const"})
+	}
+	return attrs
+}
+
+// declAttrs converts the metadata attributes of a field or parameter, other than
+// NativeArrayInfo, which is part of their type, and Const, which is reported separately.
+// Flag attributes are converted to their name, the others to Props.
+func (this *reader) declAttrs(table int, row int) ([]jsonmodel.Attr, bool) {
+	var attrs []jsonmodel.Attr
+	var isConst bool
+	for _, it := range this.attributes(table, row) {
+		if it.namespace != attrNamespace || it.name == "NativeArrayInfo" {
+			continue
+		}
+		if it.name == "Const" {
+			isConst = true
+			continue
+		}
+		if len(it.args) == 0 && len(it.named) == 0 {
+			attrs = append(attrs, jsonmodel.Attr{Str: it.name})
+			continue
+		}
+		props := map[string]interface{}{"Kind": it.name}
+		for n, argName := range attrArgNames[it.name] {
+			if n < len(it.args) {
+				props[argName] = jsonValue(it.args[n])
+			}
+		}
+		for k, v := range it.named {
+			props[k] = jsonValue(v)
+		}
+		attrs = append(attrs, jsonmodel.Attr{Props: props})
+	}
+	if table == tField && isConst {
+		attrs = append(attrs, jsonmodel.Attr{Str: "Const"})
+	}
+	return attrs, isConst
+}
+
+// jsonValue returns the attribute value as decoded from json, numbers as float64
+func jsonValue(v interface{}) interface{} {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v
+}
+
+// constant returns the value of the Constant row of the row of table, nil if there is none
+func (this *reader) constant(table int, row int) (interface{}, byte) {
+	it, ok := this.constants[encodeIndex(cHasConstant, table, row)]
+	if !ok {
+		return nil, 0
+	}
+	t := &this.md.tables[tConstant]
+	elem := byte(t.get(it, 0))
+	return constantValue(elem, this.md.blob(t.get(it, 2))), elem
+}
+
+// readConstant converts the static field row of an Apis class, guid and property key
+// constants have their value in attributes
+func (this *reader) readConstant(row int) *jsonmodel.Constant {
+	if this.md.tables[tField].get(row, 0)&fieldStatic == 0 {
+		return nil
+	}
+	c := &jsonmodel.Constant{
+		Name: this.md.string(this.md.tables[tField].get(row, 1)),
+		Type: this.typeRef(this.fieldType(row)),
+	}
+	v, elem := this.constant(tField, row)
+	if v != nil {
+		c.ValueType = nativeTypeNames[elem]
+		setConstantValue(&c.Value, v)
+		return c
+	}
+	if attr, ok := this.findAttribute(tField, row, "Guid"); ok {
+		c.ValueType = "String"
+		c.Value.Str = attr.guid(0)
+		return c
+	}
+	if attr, ok := this.findAttribute(tField, row, "PropertyKey"); ok {
+		c.ValueType = "PropertyKey"
+		c.Value.Str = fmt.Sprintf(`{"Fmtid": "%s", "Pid": %d}`, attr.guid(0), attr.intArg(11))
+		return c
+	}
+	return nil
+}
+
+// setConstantValue sets cv as decoded from json: integral numbers as Int,
+// other numbers formatted, strings escaped
+func setConstantValue(cv *jsonmodel.ConstantValue, v interface{}) {
+	switch v := v.(type) {
+	case int64:
+		cv.Int = big.NewInt(v)
+	case uint64:
+		cv.Int = new(big.Int).SetUint64(v)
+	case bool:
+		cv.Str = strconv.FormatBool(v)
+	case float32:
+		setFloatValue(cv, float64(v), strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		setFloatValue(cv, v, strconv.FormatFloat(v, 'g', -1, 64))
+	case []uint16:
+		s := strconv.Quote(string(utf16.Decode(v)))
+		cv.Str = s[1 : len(s)-1]
+	}
+}
+
+func setFloatValue(cv *jsonmodel.ConstantValue, v float64, s string) {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		cv.Int = big.NewInt(int64(v))
+	} else {
+		cv.Str = s
+	}
+}
+
+// typeRef converts a signature type to a type reference
+func (this *reader) typeRef(t *sigType) *jsonmodel.Type {
+	switch t.elem {
+	case elemPtr, elemByRef:
+		return &jsonmodel.Type{Kind: "PointerTo", Child: this.typeRef(t.child)}
+	case elemArray:
+		arr := &jsonmodel.Type{Kind: "Array", Child: this.typeRef(t.child)}
+		arr.Shape.Size = t.size
+		return arr
+	case elemValueType, elemClass:
+		return this.namedTypeRef(t)
+	}
+	name, ok := nativeTypeNames[t.elem]
+	if !ok {
+		panic(fmt.Errorf("unsupported element type %#x", t.elem))
+	}
+	return &jsonmodel.Type{Kind: "Native", Name: name}
+}
+
+// namedTypeRef converts a reference to a TypeDef or TypeRef, to an ApiRef unless
+// it is a System type
+func (this *reader) namedTypeRef(t *sigType) *jsonmodel.Type {
+	name, ns := this.typeName(t.table, t.row)
+	if ns == "System" {
+		if name == "Guid" {
+			return &jsonmodel.Type{Kind: "Native", Name: "Guid"}
+		}
+		return &jsonmodel.Type{Kind: "Native", Name: name}
+	}
+	ref := &jsonmodel.Type{
+		Kind:       "ApiRef",
+		Name:       name,
+		TargetKind: "Default",
+		Api:        strings.TrimPrefix(ns, nsPrefix),
+		Parents:    []string{},
+	}
+	if t.table != tTypeDef {
+		if t.elem == elemClass {
+			ref.TargetKind = "Com"
+		}
+		return ref
+	}
+	if this.md.tables[tTypeDef].get(t.row, 0)&typeInterface != 0 {
+		ref.TargetKind = "Com"
+	} else if this.baseType(t.row) == "System.MulticastDelegate" {
+		ref.TargetKind = "FunctionPointer"
+	}
+	for it, ok := this.enclosing[t.row]; ok; it, ok = this.enclosing[it] {
+		parent, _ := this.typeName(tTypeDef, it)
+		ref.Parents = append([]string{parent}, ref.Parents...)
+	}
+	return ref
+}
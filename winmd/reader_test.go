@@ -0,0 +1,276 @@
+package winmd
+
+import (
+	"go-win32api-gen/jsonmodel"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// typeString formats a type reference, e.g. PointerTo(ApiRef Foundation.PWSTR)
+func typeString(t *jsonmodel.Type) string {
+	switch t.Kind {
+	case "PointerTo":
+		return "PointerTo(" + typeString(t.Child) + ")"
+	case "Array":
+		return "Array[" + strconv.Itoa(t.Shape.Size) + "](" + typeString(t.Child) + ")"
+	case "LPArray":
+		return "LPArray[" + strconv.Itoa(t.CountConst) + "," + strconv.Itoa(t.CountParamIndex) + "](" +
+			typeString(t.Child) + ")"
+	case "ApiRef":
+		name := strings.Join(append([]string{t.Api}, append(t.Parents, t.Name)...), ".")
+		if t.TargetKind != "Default" {
+			name += " " + t.TargetKind
+		}
+		return "ApiRef " + name
+	default:
+		return t.Kind + " " + t.Name
+	}
+}
+
+// attrStrings returns the flag attributes, the others are checked by their Props
+func attrStrings(attrs []jsonmodel.Attr) []string {
+	var s []string
+	for _, it := range attrs {
+		if it.Props == nil {
+			s = append(s, it.Str)
+		}
+	}
+	return s
+}
+
+func paramStrings(f *jsonmodel.Function) []string {
+	var s []string
+	for _, p := range f.Params {
+		s = append(s, p.Name+" "+typeString(p.Type)+" "+strings.Join(attrStrings(p.Attrs), ","))
+	}
+	return s
+}
+
+type fixture struct {
+	t    *testing.T
+	apis map[string]*jsonmodel.Api
+}
+
+func readFixture(t *testing.T) *fixture {
+	apis, err := ReadApis(filepath.Join("testdata", "fixture.winmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{t, make(map[string]*jsonmodel.Api)}
+	for _, api := range apis {
+		f.apis[api.Name] = api
+	}
+	return f
+}
+
+func (this *fixture) typ(api string, name string) *jsonmodel.Type {
+	for _, it := range this.apis[api].Types {
+		if it.Name == name {
+			return it
+		}
+	}
+	this.t.Fatalf("type %s.%s is not read", api, name)
+	return nil
+}
+
+func (this *fixture) function(api string, name string) *jsonmodel.Function {
+	for _, it := range this.apis[api].Functions {
+		if it.Name == name {
+			return it
+		}
+	}
+	this.t.Fatalf("function %s.%s is not read", api, name)
+	return nil
+}
+
+func (this *fixture) constant(api string, name string) *jsonmodel.Constant {
+	for _, it := range this.apis[api].Constants {
+		if it.Name == name {
+			return it
+		}
+	}
+	this.t.Fatalf("constant %s.%s is not read", api, name)
+	return nil
+}
+
+func check(t *testing.T, what string, got interface{}, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %#v, want %#v", what, got, want)
+	}
+}
+
+// TestFixture reads testdata/fixture.winmd, built from testdata/fixture.cs
+func TestFixture(t *testing.T) {
+	f := readFixture(t)
+	check(t, "namespaces", len(f.apis), 3)
+
+	handle := f.typ("Foundation", "HANDLE")
+	check(t, "HANDLE", []string{handle.Kind, typeString(handle.Def), handle.FreeFunc},
+		[]string{"NativeTypedef", "Native IntPtr", "CloseHandle"})
+	check(t, "PWSTR", typeString(f.typ("Foundation", "PWSTR").Def), "PointerTo(Native Char)")
+	hWidget := f.typ("UI.Widgets", "HWIDGET")
+	check(t, "HWIDGET", []string{hWidget.AlsoUsableFor, hWidget.FreeFunc}, []string{"HANDLE", "DestroyWidget"})
+
+	win32Error := f.typ("Foundation", "WIN32_ERROR")
+	check(t, "WIN32_ERROR", []interface{}{win32Error.Kind, win32Error.IntegerBase, win32Error.Flags,
+		win32Error.Values[2].Name, win32Error.Values[2].Value.String()},
+		[]interface{}{"Enum", "UInt32", false, "WAIT_FAILED", "4294967295"})
+	style := f.typ("UI.Widgets", "WIDGET_STYLE")
+	check(t, "WIDGET_STYLE flags", style.Flags, true)
+	kind := f.typ("UI.Widgets", "WIDGET_KIND")
+	check(t, "WIDGET_KIND", []interface{}{kind.Scoped, kind.IntegerBase}, []interface{}{true, "Byte"})
+
+	info := f.typ("UI.Widgets", "WIDGET_INFO")
+	check(t, "WIDGET_INFO", []interface{}{info.Kind, info.PackingSize, info.Size}, []interface{}{"Struct", 4, 0})
+	var fields []string
+	for _, it := range info.Fields {
+		fields = append(fields, it.Name+" "+typeString(it.Type)+" "+strings.Join(attrStrings(it.Attrs), ","))
+	}
+	check(t, "WIDGET_INFO fields", fields, []string{
+		"cbSize Native UInt32 ",
+		"style ApiRef UI.Widgets.WIDGET_STYLE ",
+		"kind ApiRef UI.Widgets.WIDGET_KIND ",
+		"name ApiRef Foundation.PWSTR Const",
+		"enumProc ApiRef UI.Widgets.WIDGETENUMPROC FunctionPointer ",
+		"widget ApiRef UI.Widgets.IWidget Com ",
+		"Anonymous ApiRef UI.Widgets.WIDGET_INFO._Anonymous_e__Union ",
+	})
+	union := info.NestedTypes[0]
+	check(t, "nested union", []interface{}{union.Name, union.Kind, union.NestedTypes[0].Name,
+		typeString(union.Fields[1].Type)}, []interface{}{"_Anonymous_e__Union", "Union", "_Anonymous_e__Struct",
+		"ApiRef UI.Widgets.WIDGET_INFO._Anonymous_e__Union._Anonymous_e__Struct"})
+
+	context := f.typ("UI.Widgets", "WIDGET_CONTEXT")
+	check(t, "WIDGET_CONTEXT", []interface{}{context.Architectures, context.Size},
+		[]interface{}{[]string{"X64", "Arm64"}, 8})
+	check(t, "unicode aliases", f.apis["UI.Widgets"].UnicodeAliases, []string{"WIDGET_NAME", "CreateWidget"})
+
+	proc := f.typ("UI.Widgets", "WIDGETENUMPROC")
+	check(t, "WIDGETENUMPROC", []string{proc.Kind, typeString(proc.ReturnType), proc.Params[1].Name,
+		strings.Join(attrStrings(proc.Params[1].Attrs), ",")},
+		[]string{"FunctionPointer", "ApiRef Foundation.BOOL", "lParam", "Optional"})
+
+	iWidget := f.typ("UI.Widgets", "IWidget")
+	check(t, "IWidget", []string{iWidget.Kind, iWidget.Guid, typeString(iWidget.Interface)},
+		[]string{"Com", "6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6001", "ApiRef System.Com.IUnknown Com"})
+	check(t, "IWidget methods", len(iWidget.Methods), 4)
+	check(t, "GetName", paramStrings(iWidget.Methods[1]),
+		[]string{"name PointerTo(ApiRef Foundation.PWSTR) Out,RetVal"})
+	check(t, "GetParent", paramStrings(iWidget.Methods[2]),
+		[]string{"parent PointerTo(ApiRef UI.Widgets.IWidget Com) Out,ComOutPtr"})
+	check(t, "SetStyles", paramStrings(iWidget.Methods[3]), []string{
+		"styles LPArray[-1,1](ApiRef UI.Widgets.WIDGET_STYLE) In",
+		"count Native UInt32 In",
+	})
+	widget := f.typ("UI.Widgets", "Widget")
+	check(t, "Widget", []string{widget.Kind, widget.Guid},
+		[]string{"ComClassID", "6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6002"})
+
+	createWidget := f.function("UI.Widgets", "CreateWidgetW")
+	check(t, "CreateWidgetW", []interface{}{createWidget.DllImport, createWidget.SetLastError,
+		createWidget.Platform, typeString(createWidget.ReturnType)},
+		[]interface{}{"USER32", true, "windows6.1", "ApiRef UI.Widgets.HWIDGET"})
+	check(t, "CreateWidgetW params", paramStrings(createWidget), []string{
+		"name ApiRef Foundation.PWSTR In,Const",
+		"style ApiRef UI.Widgets.WIDGET_STYLE In",
+		"parent ApiRef Foundation.HWND In,Optional",
+	})
+	check(t, "GetWidgetData params", paramStrings(f.function("UI.Widgets", "GetWidgetData")), []string{
+		"hWidget ApiRef UI.Widgets.HWIDGET In",
+		"data PointerTo(Native Void) Out",
+		"size Native UInt32 In",
+		"reserved PointerTo(Native Void) Reserved",
+	})
+	memorySize := f.function("UI.Widgets", "GetWidgetData").Params[1].Attrs[1].Props
+	check(t, "MemorySize", memorySize, map[string]interface{}{"Kind": "MemorySize", "BytesParamIndex": 2.0})
+	check(t, "EnumWidgets ids", typeString(f.function("UI.Widgets", "EnumWidgets").Params[2].Type),
+		"LPArray[4,-1](Native UInt32)")
+	getContext := f.function("UI.Widgets", "GetWidgetContext")
+	check(t, "GetWidgetContext", []interface{}{getContext.Architectures, getContext.SetLastError,
+		typeString(getContext.ReturnType)}, []interface{}{[]string{"X64", "Arm64"}, false, "Native Void"})
+
+	for _, it := range []struct {
+		api, name, valueType, value, typ string
+	}{
+		{"Foundation", "MAX_PATH", "UInt32", "260", "Native UInt32"},
+		{"Foundation", "INVALID_FILE_SIZE", "Int32", "-1", "Native Int32"},
+		{"Foundation", "DEFAULT_NAME", "String", `wid\\get \"1\"`, "Native String"},
+		{"Foundation", "WIDGET_SCALE", "Double", "1.5", "Native Double"},
+		{"Foundation", "WIDGET_RATIO", "Single", "2", "Native Single"},
+		{"Foundation", "WIDGET_BIG", "Int64", "-9223372036854775808", "Native Int64"},
+		{"UI.Widgets", "WS_DEFAULT", "UInt32", "1", "ApiRef UI.Widgets.WIDGET_STYLE"},
+		{"UI.Widgets", "WIDGET_GUID_DEFAULT", "String", "6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6003", "Native Guid"},
+		{"UI.Widgets", "PKEY_Widget_Name", "PropertyKey",
+			`{"Fmtid": "6b0d8e6e-3c5a-4b8f-9a2e-1d4c7e5f6004", "Pid": 2}`, "ApiRef UI.Widgets.DEVPROPKEY"},
+	} {
+		c := f.constant(it.api, it.name)
+		check(t, it.name, []string{c.ValueType, c.Value.String(), typeString(c.Type)},
+			[]string{it.valueType, it.value, it.typ})
+	}
+}
+
+// TestArraySignature decodes the fixed size arrays C# cannot declare
+func TestArraySignature(t *testing.T) {
+	//field of type char[32], the only dimension has a size and no lower bound
+	r := &sigReader{[]byte{sigField, elemArray, elemChar, 1, 1, 32, 0}}
+	got := (&reader{}).typeRef(r.fieldSig())
+	check(t, "array", typeString(got), "Array[32](Native Char)")
+}
+
+func TestInvalidFile(t *testing.T) {
+	if _, err := ReadApis(filepath.Join("testdata", "fixture.cs")); err == nil {
+		t.Error("reading a file that is not a winmd succeeds")
+	}
+}
+
+// TestReadCached checks that a winmd file is read once while it is unchanged,
+// and read again when it changes
+func TestReadCached(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "fixture.winmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.winmd")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := ReadApis(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//invalid content of the same size and modification time is not read
+	if err := ioutil.WriteFile(path, make([]byte, len(data)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	second, err := ReadApis(path)
+	if err != nil {
+		t.Fatalf("the metadata is not cached: %v", err)
+	}
+	if len(first) != len(second) || len(first) == 0 || first[0] == second[0] {
+		t.Error("the reads share their declarations")
+	}
+
+	//it is once the file changes
+	modTime := fi.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadApis(path); err == nil {
+		t.Error("the changed file is not read again")
+	}
+}
@@ -0,0 +1,263 @@
+package winmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// element types, ECMA-335 II.23.1.16
+const (
+	elemVoid        = 0x01
+	elemBoolean     = 0x02
+	elemChar        = 0x03
+	elemI1          = 0x04
+	elemU1          = 0x05
+	elemI2          = 0x06
+	elemU2          = 0x07
+	elemI4          = 0x08
+	elemU4          = 0x09
+	elemI8          = 0x0a
+	elemU8          = 0x0b
+	elemR4          = 0x0c
+	elemR8          = 0x0d
+	elemString      = 0x0e
+	elemPtr         = 0x0f
+	elemByRef       = 0x10
+	elemValueType   = 0x11
+	elemClass       = 0x12
+	elemArray       = 0x14
+	elemI           = 0x18
+	elemU           = 0x19
+	elemObject      = 0x1c
+	elemSzArray     = 0x1d
+	elemCModReqd    = 0x1f
+	elemCModOpt     = 0x20
+	elemBoxed       = 0x51
+	elemEnum        = 0x55
+	sigField        = 0x06
+	sigGeneric      = 0x10
+	attrBlobProlog  = 0x0001
+	nullSerString   = 0xff
+	typeDefOrRefTag = 2
+)
+
+// the metadata names of the native types by element type
+var nativeTypeNames = map[byte]string{
+	elemVoid:    "Void",
+	elemBoolean: "Boolean",
+	elemChar:    "Char",
+	elemI1:      "SByte",
+	elemU1:      "Byte",
+	elemI2:      "Int16",
+	elemU2:      "UInt16",
+	elemI4:      "Int32",
+	elemU4:      "UInt32",
+	elemI8:      "Int64",
+	elemU8:      "UInt64",
+	elemR4:      "Single",
+	elemR8:      "Double",
+	elemString:  "String",
+	elemI:       "IntPtr",
+	elemU:       "UIntPtr",
+}
+
+// sigType is a type of a signature, ECMA-335 II.23.2.12
+type sigType struct {
+	elem  byte
+	child *sigType //elemPtr, elemByRef, elemArray
+	table int      //elemValueType, elemClass: tTypeDef or tTypeRef
+	row   int
+	size  int //elemArray: the size of the first dimension
+}
+
+// methodSig is a method signature, ECMA-335 II.23.2.1
+type methodSig struct {
+	ret    *sigType
+	params []*sigType
+}
+
+// uncompress decodes the compressed unsigned integer at the start of p,
+// it returns its value and size, ECMA-335 II.23.2
+func uncompress(p []byte) (uint32, int) {
+	switch {
+	case p[0]&0x80 == 0:
+		return uint32(p[0]), 1
+	case p[0]&0xc0 == 0x80:
+		return uint32(p[0]&0x3f)<<8 | uint32(p[1]), 2
+	default:
+		return uint32(p[0]&0x1f)<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3]), 4
+	}
+}
+
+// sigReader reads signatures and custom attribute values, it panics on malformed blobs
+type sigReader struct {
+	p []byte
+}
+
+func (this *sigReader) byte() byte {
+	b := this.p[0]
+	this.p = this.p[1:]
+	return b
+}
+
+func (this *sigReader) peek() byte {
+	return this.p[0]
+}
+
+func (this *sigReader) uint() uint32 {
+	v, n := uncompress(this.p)
+	this.p = this.p[n:]
+	return v
+}
+
+func (this *sigReader) fixed(size int) uint64 {
+	var v uint64
+	for n := size - 1; n >= 0; n-- {
+		v = v<<8 | uint64(this.p[n])
+	}
+	this.p = this.p[size:]
+	return v
+}
+
+// typeDefOrRef decodes a TypeDefOrRefOrSpecEncoded, ECMA-335 II.23.2.8
+func (this *sigReader) typeDefOrRef() (int, int) {
+	v := this.uint()
+	return codedIndexes[cTypeDefOrRef][v&(1<<typeDefOrRefTag-1)], int(v >> typeDefOrRefTag)
+}
+
+func (this *sigReader) typ() *sigType {
+	elem := this.byte()
+	for elem == elemCModReqd || elem == elemCModOpt {
+		this.typeDefOrRef()
+		elem = this.byte()
+	}
+	t := &sigType{elem: elem}
+	switch elem {
+	case elemPtr, elemByRef, elemSzArray:
+		t.child = this.typ()
+	case elemValueType, elemClass:
+		t.table, t.row = this.typeDefOrRef()
+	case elemArray:
+		t.child = this.typ()
+		this.uint() //rank
+		sizes := make([]uint32, this.uint())
+		for n := range sizes {
+			sizes[n] = this.uint()
+		}
+		for n := this.uint(); n > 0; n-- {
+			this.uint() //lower bound
+		}
+		if len(sizes) > 0 {
+			t.size = int(sizes[0])
+		}
+	default:
+		if _, ok := nativeTypeNames[elem]; !ok && elem != elemObject {
+			panic(fmt.Errorf("unsupported element type %#x", elem))
+		}
+	}
+	return t
+}
+
+func (this *sigReader) methodSig() methodSig {
+	if this.byte()&sigGeneric != 0 {
+		this.uint()
+	}
+	var sig methodSig
+	params := make([]*sigType, this.uint())
+	sig.ret = this.typ()
+	for n := range params {
+		params[n] = this.typ()
+	}
+	sig.params = params
+	return sig
+}
+
+func (this *sigReader) fieldSig() *sigType {
+	if b := this.byte(); b != sigField {
+		panic(fmt.Errorf("invalid field signature %#x", b))
+	}
+	return this.typ()
+}
+
+// serString reads a SerString, ECMA-335 II.23.3
+func (this *sigReader) serString() string {
+	if this.peek() == nullSerString {
+		this.byte()
+		return ""
+	}
+	size := this.uint()
+	s := string(this.p[:size])
+	this.p = this.p[size:]
+	return s
+}
+
+// elemValue reads a custom attribute value of the element type elem, integers are
+// returned as int64, floating point numbers as float64
+func (this *sigReader) elemValue(elem byte) interface{} {
+	switch elem {
+	case elemBoolean:
+		return this.byte() != 0
+	case elemI1:
+		return int64(int8(this.byte()))
+	case elemI2:
+		return int64(int16(this.fixed(2)))
+	case elemI4:
+		return int64(int32(this.fixed(4)))
+	case elemI8, elemU8:
+		return int64(this.fixed(8))
+	case elemU1:
+		return int64(this.byte())
+	case elemChar, elemU2:
+		return int64(this.fixed(2))
+	case elemU4:
+		return int64(this.fixed(4))
+	case elemR4:
+		return float64(math.Float32frombits(uint32(this.fixed(4))))
+	case elemR8:
+		return math.Float64frombits(this.fixed(8))
+	case elemString:
+		return this.serString()
+	case elemBoxed:
+		return this.elemValue(this.byte())
+	default:
+		panic(fmt.Errorf("unsupported custom attribute value type %#x", elem))
+	}
+}
+
+// constantValue decodes the value of a Constant row of the element type elem,
+// integers are returned as int64 or uint64, strings as their UTF-16 code units
+func constantValue(elem byte, p []byte) interface{} {
+	switch elem {
+	case elemBoolean:
+		return p[0] != 0
+	case elemI1:
+		return int64(int8(p[0]))
+	case elemI2:
+		return int64(int16(binary.LittleEndian.Uint16(p)))
+	case elemI4:
+		return int64(int32(binary.LittleEndian.Uint32(p)))
+	case elemI8:
+		return int64(binary.LittleEndian.Uint64(p))
+	case elemU1:
+		return uint64(p[0])
+	case elemChar, elemU2:
+		return uint64(binary.LittleEndian.Uint16(p))
+	case elemU4:
+		return uint64(binary.LittleEndian.Uint32(p))
+	case elemU8:
+		return binary.LittleEndian.Uint64(p)
+	case elemR4:
+		return math.Float32frombits(binary.LittleEndian.Uint32(p))
+	case elemR8:
+		return math.Float64frombits(binary.LittleEndian.Uint64(p))
+	case elemString:
+		chars := make([]uint16, len(p)/2)
+		for n := range chars {
+			chars[n] = binary.LittleEndian.Uint16(p[2*n:])
+		}
+		return chars
+	default:
+		return nil
+	}
+}
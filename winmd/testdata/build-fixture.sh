@@ -0,0 +1,12 @@
+#!/bin/sh
+# Builds fixture.winmd from fixture.cs with the C# compiler of a .NET SDK.
+set -e
+cd "$(dirname "$0")"
+DOTNET=${DOTNET:-dotnet}
+SDK=$($DOTNET --list-sdks | tail -1 | sed 's/^\([^ ]*\) \[\(.*\)\]$/\2\/\1/')
+REF=$(dirname "$(dirname "$SDK")")/packs/Microsoft.NETCore.App.Ref
+REF=$(ls -d "$REF"/*/ref/net* | tail -1)
+$DOTNET "$SDK/Roslyn/bincore/csc.dll" -nologo -noconfig -nostdlib -unsafe -deterministic \
+	-target:library -out:fixture.winmd \
+	-r:"$REF/System.Runtime.dll" -r:"$REF/System.Runtime.InteropServices.dll" \
+	fixture.cs
@@ -0,0 +1,218 @@
+// Source of fixture.winmd, a small metadata file laid out like Windows.Win32.winmd.
+// Rebuild it with build-fixture.sh after editing, then update fixture.json with
+//   go test -run TestFixture -update ./winmd
+#pragma warning disable CS0169, CS0649, CS8500
+
+using System;
+using System.Runtime.InteropServices;
+using System.Runtime.Versioning;
+
+namespace Windows.Win32.Foundation.Metadata
+{
+    [Flags]
+    public enum Architecture { None = 0, X86 = 1, X64 = 2, Arm64 = 4, All = 7 }
+
+    public class NativeTypedefAttribute : Attribute { }
+    public class AlsoUsableForAttribute : Attribute { public AlsoUsableForAttribute(string type) { } }
+    public class RAIIFreeAttribute : Attribute { public RAIIFreeAttribute(string func) { } }
+    public class GuidAttribute : Attribute
+    {
+        public GuidAttribute(uint a, ushort b, ushort c, byte d, byte e, byte f, byte g, byte h, byte i, byte j, byte k) { }
+    }
+    public class PropertyKeyAttribute : Attribute
+    {
+        public PropertyKeyAttribute(uint a, ushort b, ushort c, byte d, byte e, byte f, byte g, byte h, byte i, byte j, byte k, uint pid) { }
+    }
+    public class SupportedArchitectureAttribute : Attribute { public SupportedArchitectureAttribute(Architecture arch) { } }
+    public class ScopedEnumAttribute : Attribute { }
+    public class ConstAttribute : Attribute { }
+    public class ReservedAttribute : Attribute { }
+    public class RetValAttribute : Attribute { }
+    public class ComOutPtrAttribute : Attribute { }
+    public class NotNullTerminatedAttribute : Attribute { }
+    public class NullNullTerminatedAttribute : Attribute { }
+    public class FreeWithAttribute : Attribute { public FreeWithAttribute(string func) { } }
+    public class NativeArrayInfoAttribute : Attribute
+    {
+        public int CountConst;
+        public short CountParamIndex;
+    }
+    public class MemorySizeAttribute : Attribute { public short BytesParamIndex; }
+}
+
+namespace Windows.Win32.Foundation
+{
+    using Windows.Win32.Foundation.Metadata;
+
+    [NativeTypedef, RAIIFree("CloseHandle")]
+    public struct HANDLE { public IntPtr Value; }
+
+    [NativeTypedef]
+    public struct HWND { public IntPtr Value; }
+
+    [NativeTypedef]
+    public struct BOOL { public int Value; }
+
+    [NativeTypedef]
+    public struct HRESULT { public int Value; }
+
+    [NativeTypedef]
+    public unsafe struct PWSTR { public char* Value; }
+
+    public enum WIN32_ERROR : uint
+    {
+        NO_ERROR = 0,
+        ERROR_ACCESS_DENIED = 5,
+        WAIT_FAILED = 4294967295,
+    }
+
+    public static unsafe class Apis
+    {
+        public const uint MAX_PATH = 260;
+        public const int INVALID_FILE_SIZE = -1;
+        public const string DEFAULT_NAME = "wid\\get \"1\"";
+        public const double WIDGET_SCALE = 1.5;
+        public const float WIDGET_RATIO = 2;
+        public const long WIDGET_BIG = -9223372036854775808;
+
+        [DllImport("KERNEL32.dll", ExactSpelling = true, SetLastError = true)]
+        [SupportedOSPlatform("windows5.0")]
+        public static extern BOOL CloseHandle([In] HANDLE hObject);
+    }
+}
+
+namespace Windows.Win32.System.Com
+{
+    using Windows.Win32.Foundation;
+    using Windows.Win32.Foundation.Metadata;
+
+    [Guid(0x00000000, 0x0000, 0x0000, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46)]
+    public unsafe interface IUnknown
+    {
+        HRESULT QueryInterface([In, Const] Guid* riid, [Out, ComOutPtr] void** ppvObject);
+        uint AddRef();
+        uint Release();
+    }
+}
+
+namespace Windows.Win32.UI.Widgets
+{
+    using Windows.Win32.Foundation;
+    using Windows.Win32.Foundation.Metadata;
+    using Windows.Win32.System.Com;
+
+    [Flags]
+    public enum WIDGET_STYLE : uint
+    {
+        WS_NONE = 0,
+        WS_BORDER = 1,
+        WS_SHADOW = 2,
+    }
+
+    [ScopedEnum]
+    public enum WIDGET_KIND : byte
+    {
+        Button = 1,
+        Label = 2,
+    }
+
+    [NativeTypedef, AlsoUsableFor("HANDLE"), RAIIFree("DestroyWidget")]
+    public struct HWIDGET { public IntPtr Value; }
+
+    public unsafe delegate BOOL WIDGETENUMPROC(HWIDGET hWidget, [Optional] IntPtr lParam);
+
+    [StructLayout(LayoutKind.Sequential, Pack = 4)]
+    public unsafe struct WIDGET_INFO
+    {
+        public uint cbSize;
+        public WIDGET_STYLE style;
+        public WIDGET_KIND kind;
+        [Const] public PWSTR name;
+        public WIDGETENUMPROC enumProc;
+        public IWidget widget;
+        public _Anonymous_e__Union Anonymous;
+
+        [StructLayout(LayoutKind.Explicit)]
+        public struct _Anonymous_e__Union
+        {
+            [FieldOffset(0)] public uint Value;
+            [FieldOffset(0)] public _Anonymous_e__Struct Parts;
+
+            public struct _Anonymous_e__Struct
+            {
+                public ushort Low;
+                public ushort High;
+            }
+        }
+    }
+
+    [StructLayout(LayoutKind.Sequential, Size = 8)]
+    [SupportedArchitecture(Architecture.X64 | Architecture.Arm64)]
+    public struct WIDGET_CONTEXT
+    {
+        public ulong Rip;
+    }
+
+    [StructLayout(LayoutKind.Sequential, Size = 4)]
+    [SupportedArchitecture(Architecture.X86)]
+    public struct WIDGET_CONTEXT_X86
+    {
+        public uint Eip;
+    }
+
+    public struct WIDGET_NAMEA { public byte First; }
+
+    public struct WIDGET_NAMEW { public char First; }
+
+    [Guid(0x6b0d8e6e, 0x3c5a, 0x4b8f, 0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x01)]
+    public unsafe interface IWidget : IUnknown
+    {
+        HRESULT GetInfo([Out] WIDGET_INFO* info);
+        HRESULT GetName([Out, RetVal] PWSTR* name);
+        HRESULT GetParent([Out, ComOutPtr] IWidget* parent);
+        HRESULT SetStyles([In, NativeArrayInfo(CountParamIndex = 1)] WIDGET_STYLE* styles, [In] uint count);
+    }
+
+    [Guid(0x6b0d8e6e, 0x3c5a, 0x4b8f, 0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x02)]
+    public class Widget { }
+
+    public static unsafe class Apis
+    {
+        public const WIDGET_STYLE WS_DEFAULT = WIDGET_STYLE.WS_BORDER;
+
+        [Guid(0x6b0d8e6e, 0x3c5a, 0x4b8f, 0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x03)]
+        public static readonly Guid WIDGET_GUID_DEFAULT;
+
+        [PropertyKey(0x6b0d8e6e, 0x3c5a, 0x4b8f, 0x9a, 0x2e, 0x1d, 0x4c, 0x7e, 0x5f, 0x60, 0x04, 2)]
+        public static readonly DEVPROPKEY PKEY_Widget_Name;
+
+        [DllImport("USER32.dll", ExactSpelling = true, SetLastError = true)]
+        [SupportedOSPlatform("windows6.1")]
+        public static extern HWIDGET CreateWidgetW([In, Const] PWSTR name, [In] WIDGET_STYLE style, [In, Optional] HWND parent);
+
+        [DllImport("USER32.dll", ExactSpelling = true, SetLastError = true)]
+        public static extern HWIDGET CreateWidgetA([In, Const] byte* name, [In] WIDGET_STYLE style, [In, Optional] HWND parent);
+
+        [DllImport("USER32.dll", ExactSpelling = true)]
+        public static extern BOOL DestroyWidget([In] HWIDGET hWidget);
+
+        [DllImport("USER32.dll", ExactSpelling = true, SetLastError = true)]
+        public static extern uint GetWidgetData([In] HWIDGET hWidget, [Out, MemorySize(BytesParamIndex = 2)] void* data, [In] uint size, [Reserved] void* reserved);
+
+        [DllImport("USER32.dll", ExactSpelling = true)]
+        public static extern BOOL EnumWidgets([In] WIDGETENUMPROC proc, [In] IntPtr lParam, [In, NativeArrayInfo(CountConst = 4)] uint* ids);
+
+        [DllImport("USER32.dll", ExactSpelling = true)]
+        [SupportedArchitecture(Architecture.X64 | Architecture.Arm64)]
+        public static extern void GetWidgetContext([In] HWIDGET hWidget, [Out] WIDGET_CONTEXT* context);
+
+        [DllImport("OLE32.dll", ExactSpelling = true)]
+        public static extern HRESULT CoCreateInstance([In, Const] Guid* rclsid, [In, Const] Guid* riid, [Out, ComOutPtr] void** ppv);
+    }
+
+    public struct DEVPROPKEY
+    {
+        public Guid fmtid;
+        public uint pid;
+    }
+}